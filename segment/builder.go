@@ -0,0 +1,51 @@
+// Copyright (c) 2018, Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segment
+
+import "fmt"
+
+// Builder accumulates segments, and normalizes them once when Build is called,
+// so that building a set from streaming input does not sort it on every insertion.
+// The zero value is an empty Builder ready to use.
+type Builder struct {
+	segments Segments
+}
+
+// Add adds the segment from start to end to the builder.
+// If end < start, the segment is not well-defined, so an error is returned, and nothing is added.
+func (b *Builder) Add(start, end int64) error {
+	if end < start {
+		return fmt.Errorf("end < start: segment not added")
+	}
+	b.segments = append(b.segments, Segment{start, end})
+	return nil
+}
+
+// AddSegment adds a segment to the builder.
+func (b *Builder) AddSegment(s Segment) {
+	b.segments = append(b.segments, s)
+}
+
+// Len returns the number of segments added to the builder so far.
+func (b *Builder) Len() int {
+	return len(b.segments)
+}
+
+// Build returns the union of the segments added so far, as per RemoveOverlaps:
+// sorted by start, and without overlaps. The builder is not reset, so more
+// segments can be added and Build called again.
+func (b *Builder) Build() Segments {
+	return RemoveOverlaps(b.segments)
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2018, Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segment
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Please keep the order of test functions the same as
+// the order of methods/functions in builder.go.
+
+func TestBuilderAdd(t *testing.T) {
+	var b Builder
+	if err := b.Add(0, 5); err != nil {
+		t.Errorf("Add(0, 5) returned error %v", err)
+	}
+	if err := b.Add(5, 3); err == nil {
+		t.Errorf("Add(5, 3) returned no error")
+	}
+	b.AddSegment(Segment{10, 12})
+	if got, want := b.Len(), 2; got != want {
+		t.Errorf("Len() = %d, want %d", got, want)
+	}
+}
+
+func TestBuilderBuild(t *testing.T) {
+	var b Builder
+	if got := b.Build(); got != nil {
+		t.Errorf("Build() on an empty builder = %s, want nil", got)
+	}
+
+	b.AddSegment(Segment{10, 12})
+	b.Add(0, 5)
+	b.Add(4, 8)
+	want := Segments{Segment{0, 8}, Segment{10, 12}}
+	if got := b.Build(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Build() = %s, want %s", got, want)
+	}
+
+	// The builder keeps its segments, so building again after adding more gives their union.
+	b.Add(8, 10)
+	want = Segments{Segment{0, 12}}
+	if got := b.Build(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Build() = %s, want %s", got, want)
+	}
+}
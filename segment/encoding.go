@@ -0,0 +1,279 @@
+// Copyright (c) 2018, Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segment
+
+import (
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//////// JSON ENCODING ////////
+
+// jsonSegment is the JSON object form of a Segment.
+// The fields are pointers so that missing fields can be detected.
+type jsonSegment struct {
+	Start *int64 `json:"start"`
+	End   *int64 `json:"end"`
+}
+
+// MarshalJSON implements json.Marshaler, encoding a segment as an object
+// such as {"start":11,"end":13}. Segments are encoded as an array of such objects.
+func (s Segment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonSegment{&s.start, &s.end})
+}
+
+// UnmarshalJSON implements json.Unmarshaler, decoding an object as written by MarshalJSON.
+// If a field is missing, or end < start, an error is returned and the segment is not updated.
+func (s *Segment) UnmarshalJSON(data []byte) error {
+	var js jsonSegment
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	if js.Start == nil || js.End == nil {
+		return fmt.Errorf("missing start or end: nil segment returned")
+	}
+	decoded, err := New(*js.Start, *js.End)
+	if err != nil {
+		return err
+	}
+	*s = decoded
+	return nil
+}
+
+// MarshalPairs encodes the segments as a JSON array of two-element arrays,
+// such as [[11,13],[20,25]], as an alternative to the object form of MarshalJSON.
+// Empty input is encoded as [].
+func (ss Segments) MarshalPairs() ([]byte, error) {
+	pairs := make([][2]int64, 0, len(ss))
+	for _, s := range ss {
+		pairs = append(pairs, [2]int64{s.start, s.end})
+	}
+	return json.Marshal(pairs)
+}
+
+// UnmarshalPairs decodes segments as written by MarshalPairs.
+// It returns an error naming the pair if a pair does not have exactly two
+// elements, or has end < start.
+func UnmarshalPairs(data []byte) (Segments, error) {
+	var pairs [][]int64
+	if err := json.Unmarshal(data, &pairs); err != nil {
+		return nil, err
+	}
+	var output Segments
+	for i, pair := range pairs {
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("pair %d has %d elements: segments not unmarshaled", i, len(pair))
+		}
+		s, err := New(pair[0], pair[1])
+		if err != nil {
+			return nil, fmt.Errorf("pair %d: %v", i, err)
+		}
+		output = append(output, s)
+	}
+	return output, nil
+}
+
+//////// GOB ENCODING ////////
+
+// GobEncode implements gob.GobEncoder, encoding the start and end of a segment as varints.
+func (s Segment) GobEncode() ([]byte, error) {
+	data := binary.AppendVarint(nil, s.start)
+	return binary.AppendVarint(data, s.end), nil
+}
+
+// GobDecode implements gob.GobDecoder, decoding a segment as written by GobEncode.
+// If the data is malformed, or end < start, an error is returned and the segment is not updated.
+func (s *Segment) GobDecode(data []byte) error {
+	start, n := binary.Varint(data)
+	if n <= 0 {
+		return fmt.Errorf("malformed start: segment not decoded")
+	}
+	end, m := binary.Varint(data[n:])
+	if m <= 0 || n+m != len(data) {
+		return fmt.Errorf("malformed end: segment not decoded")
+	}
+	decoded, err := New(start, end)
+	if err != nil {
+		return err
+	}
+	*s = decoded
+	return nil
+}
+
+//////// FLAT ENCODING ////////
+
+// Flatten returns the segments as a flat slice of endpoints, in the form
+// [start0, end0, start1, end1, ...]. This maps onto a repeated int64 proto field.
+func (ss Segments) Flatten() []int64 {
+	var output []int64
+	for _, s := range ss {
+		output = append(output, s.start, s.end)
+	}
+	return output
+}
+
+// Unflatten is the inverse of Flatten. It returns an error if vals has an odd
+// length, or if any pair has end < start.
+func Unflatten(vals []int64) (Segments, error) {
+	if len(vals)%2 != 0 {
+		return nil, fmt.Errorf("odd number of values (%d): segments not unflattened", len(vals))
+	}
+	var output Segments
+	for i := 0; i < len(vals); i += 2 {
+		s, err := New(vals[i], vals[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("pair %d: %v", i/2, err)
+		}
+		output = append(output, s)
+	}
+	return output, nil
+}
+
+//////// CSV ENCODING ////////
+
+// csvHeader is the header row written by WriteCSV, and optionally read by ReadCSV.
+var csvHeader = []string{"start", "end"}
+
+// WriteCSV writes the segments to w as CSV, with a "start,end" header row
+// followed by one row per segment.
+func (ss Segments) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, s := range ss {
+		if err := cw.Write([]string{strconv.FormatInt(s.start, 10), strconv.FormatInt(s.end, 10)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadCSV reads segments written by WriteCSV from r. The header row is optional.
+// It returns an error naming the row if a row does not have exactly two integer
+// fields, or has end < start.
+func ReadCSV(r io.Reader) (Segments, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader)
+	cr.TrimLeadingSpace = true
+	var output Segments
+	for row := 1; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return output, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+		if row == 1 && record[0] == csvHeader[0] && record[1] == csvHeader[1] {
+			continue
+		}
+		start, err := strconv.ParseInt(record[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid start: %v", row, err)
+		}
+		end, err := strconv.ParseInt(record[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: invalid end: %v", row, err)
+		}
+		s, err := New(start, end)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+		output = append(output, s)
+	}
+}
+
+//////// RANGE LIST ENCODING ////////
+
+// RangeList returns the segments as a compact, human-editable range list,
+// such as "1-3,5,8-10", as per CompactString. Point segments are written as a
+// single number. Segments are written in order, so ParseRangeList reads the
+// output back only if ss is sorted by start and has no overlaps.
+func (ss Segments) RangeList() string {
+	return ss.CompactString()
+}
+
+// ParseRangeList parses a range list in the format written by RangeList.
+// Whitespace around numbers is ignored, and an empty list gives nil.
+// It returns an error if a range has end < start, or if the ranges are not
+// sorted by start without overlapping, naming the offending range.
+func ParseRangeList(s string) (Segments, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var output Segments
+	for _, token := range strings.Split(s, ",") {
+		seg, err := parseRange(token)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(output); n > 0 && seg.start <= output[n-1].end {
+			return nil, fmt.Errorf("range %q is out of order: range list not parsed", strings.TrimSpace(token))
+		}
+		output = append(output, seg)
+	}
+	return output, nil
+}
+
+// Parse parses segments in the format written by CompactString, such as
+// "11-13,20-25,30". Unlike ParseRangeList, the segments may be in any order
+// and may overlap; they are returned in input order. Whitespace around numbers
+// is ignored, and an empty string gives nil. It returns an error naming the
+// offending token if a token is malformed, or has end < start.
+func Parse(s string) (Segments, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var output Segments
+	for _, token := range strings.Split(s, ",") {
+		seg, err := parseRange(token)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, seg)
+	}
+	return output, nil
+}
+
+// parseRange parses a single "start-end" or "point" range.
+// The separating dash is the first one after the first character, so that
+// negative numbers such as "-5--3" parse correctly.
+func parseRange(token string) (Segment, error) {
+	token = strings.TrimSpace(token)
+	startText, endText := token, token
+	if i := strings.Index(token[min(1, len(token)):], "-"); i >= 0 {
+		startText, endText = token[:i+1], token[i+2:]
+	}
+	start, err := strconv.ParseInt(strings.TrimSpace(startText), 10, 64)
+	if err != nil {
+		return Segment{}, fmt.Errorf("range %q: invalid start: %v", token, err)
+	}
+	end, err := strconv.ParseInt(strings.TrimSpace(endText), 10, 64)
+	if err != nil {
+		return Segment{}, fmt.Errorf("range %q: invalid end: %v", token, err)
+	}
+	seg, err := New(start, end)
+	if err != nil {
+		return Segment{}, fmt.Errorf("range %q: %v", token, err)
+	}
+	return seg, nil
+}
@@ -0,0 +1,509 @@
+// Copyright (c) 2018, Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segment
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Please keep the order of test functions the same as
+// the order of methods/functions in encoding.go.
+
+func TestMarshalJSON(t *testing.T) {
+	testCases := []struct {
+		input interface{}
+		want  string
+	}{
+		{
+			input: Segment{11, 13},
+			want:  `{"start":11,"end":13}`,
+		},
+		{
+			input: Segments{
+				Segment{11, 13},
+				Segment{-2, -2},
+			},
+			want: `[{"start":11,"end":13},{"start":-2,"end":-2}]`,
+		},
+	}
+
+	for _, test := range testCases {
+		got, err := json.Marshal(test.input)
+		if err != nil || string(got) != test.want {
+			t.Errorf("json.Marshal(%s) = %s, %v, should be %s", test.input, got, err, test.want)
+		}
+	}
+
+	ss := Segments{Segment{11, 13}, Segment{0, 0}}
+	data, err := json.Marshal(ss)
+	if err != nil {
+		t.Fatalf("json.Marshal(%s) returned error %v", ss, err)
+	}
+	var back Segments
+	if err := json.Unmarshal(data, &back); err != nil || !reflect.DeepEqual(back, ss) {
+		t.Errorf("json.Unmarshal(%s) = %s, %v; should round-trip to %s", data, back, err, ss)
+	}
+}
+
+func TestUnmarshalJSON(t *testing.T) {
+	_, newErr := New(1, 0)
+	testCases := []struct {
+		data    string
+		want    Segment
+		wanterr string
+	}{
+		{
+			data: `{"start": 11, "end": 13}`,
+			want: Segment{11, 13},
+		},
+		{
+			data:    `{"start": 13, "end": 11}`,
+			want:    Segment{1, 2},
+			wanterr: newErr.Error(),
+		},
+		{
+			data:    `{"start": 13}`,
+			want:    Segment{1, 2},
+			wanterr: "missing start or end: nil segment returned",
+		},
+	}
+
+	for _, test := range testCases {
+		got := Segment{1, 2}
+		goterr := json.Unmarshal([]byte(test.data), &got)
+		goterrString := ""
+		if goterr != nil {
+			goterrString = goterr.Error()
+		}
+		if got != test.want || goterrString != test.wanterr {
+			t.Errorf("json.Unmarshal(%s) = %s, %q, should be %s, %q", test.data, got, goterrString, test.want, test.wanterr)
+		}
+	}
+}
+
+func TestWeightedSegmentJSON(t *testing.T) {
+	w := WeightedSegment{Segment{0, 10}, 7}
+	data, err := json.Marshal(w)
+	if want := `{"Seg":{"start":0,"end":10},"Weight":7}`; err != nil || string(data) != want {
+		t.Errorf("json.Marshal(%v) = %s, %v, should be %s", w, data, err, want)
+	}
+	var back WeightedSegment
+	if err := json.Unmarshal(data, &back); err != nil || back != w {
+		t.Errorf("json.Unmarshal(%s) = %v, %v; should round-trip to %v", data, back, err, w)
+	}
+}
+
+func TestMarshalPairs(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  string
+	}{
+		{
+			input: Segments{Segment{11, 13}, Segment{20, 25}},
+			want:  "[[11,13],[20,25]]",
+		},
+		{
+			input: nil,
+			want:  "[]",
+		},
+	}
+
+	for _, test := range testCases {
+		got, err := test.input.MarshalPairs()
+		if err != nil || string(got) != test.want {
+			t.Errorf("%s.MarshalPairs() = %s, %v, should be %s", test.input, got, err, test.want)
+		}
+	}
+}
+
+func TestUnmarshalPairs(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    Segments
+		wanterr string
+	}{
+		{
+			input: "[[11,13],[20,25],[30,30]]",
+			want:  Segments{Segment{11, 13}, Segment{20, 25}, Segment{30, 30}},
+		},
+		{
+			input: "[]",
+			want:  nil,
+		},
+		{
+			input:   "[[11,13],[25,20]]",
+			wanterr: "pair 1: end < start: nil segment returned",
+		},
+		{
+			input:   "[[11,13,15]]",
+			wanterr: "pair 0 has 3 elements: segments not unmarshaled",
+		},
+		{
+			input:   "[[11]]",
+			wanterr: "pair 0 has 1 elements: segments not unmarshaled",
+		},
+	}
+
+	for _, test := range testCases {
+		got, goterr := UnmarshalPairs([]byte(test.input))
+		goterrstr := ""
+		if goterr != nil {
+			goterrstr = goterr.Error()
+		}
+		if !reflect.DeepEqual(got, test.want) || goterrstr != test.wanterr {
+			t.Errorf("UnmarshalPairs(%s) = %s, %q, should be %s, %q", test.input, got, goterrstr, test.want, test.wanterr)
+		}
+	}
+
+	// Object-style JSON is not accepted as pairs.
+	objects := `[{"start":1,"end":2}]`
+	if got, err := UnmarshalPairs([]byte(objects)); err == nil {
+		t.Errorf("UnmarshalPairs(%s) = %s, want an error", objects, got)
+	}
+}
+
+func TestGob(t *testing.T) {
+	ss := Segments{
+		Segment{11, 13},
+		Segment{-5, 0},
+		Segment{math.MinInt64, math.MaxInt64},
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(ss); err != nil {
+		t.Fatalf("Encode(%s) returned error %v", ss, err)
+	}
+	var back Segments
+	if err := gob.NewDecoder(&buf).Decode(&back); err != nil || !reflect.DeepEqual(back, ss) {
+		t.Errorf("Decode() = %s, %v; should round-trip to %s", back, err, ss)
+	}
+}
+
+func TestWeightedSegmentGob(t *testing.T) {
+	ws := []WeightedSegment{{Segment{0, 10}, 7}, {Segment{-5, 0}, -1}}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(ws); err != nil {
+		t.Fatalf("Encode(%v) returned error %v", ws, err)
+	}
+	var back []WeightedSegment
+	if err := gob.NewDecoder(&buf).Decode(&back); err != nil || !reflect.DeepEqual(back, ws) {
+		t.Errorf("Decode() = %v, %v; should round-trip to %v", back, err, ws)
+	}
+}
+
+func TestGobDecode(t *testing.T) {
+	inverted, _ := (Segment{13, 11}).GobEncode()
+	testCases := []struct {
+		data    []byte
+		wanterr bool
+	}{
+		{
+			data:    inverted,
+			wanterr: true,
+		},
+		{
+			data:    nil,
+			wanterr: true,
+		},
+		{
+			data:    append(inverted, 0),
+			wanterr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		got := Segment{1, 2}
+		if goterr := got.GobDecode(test.data); got != (Segment{1, 2}) || (goterr != nil) != test.wanterr {
+			t.Errorf("GobDecode(%v) gives %s; got error? %t, want error? %t", test.data, got, goterr != nil, test.wanterr)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  []int64
+	}{
+		{
+			input: Segments{
+				Segment{2, 3},
+				Segment{1, 2},
+				Segment{4, 4},
+			},
+			want: []int64{2, 3, 1, 2, 4, 4},
+		},
+		{
+			input: Segments{},
+			want:  nil,
+		},
+	}
+
+	for _, test := range testCases {
+		got := test.input.Flatten()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.Flatten() = %v, should be %v", test.input, got, test.want)
+		}
+		// Unflatten of no values is nil, so only check round-trips of non-empty input.
+		if back, err := Unflatten(got); len(test.input) > 0 && (err != nil || !reflect.DeepEqual(back, test.input)) {
+			t.Errorf("Unflatten(%v) = %s, %v; should round-trip to %s", got, back, err, test.input)
+		}
+	}
+}
+
+func TestUnflatten(t *testing.T) {
+	testCases := []struct {
+		vals    []int64
+		want    Segments
+		wanterr bool
+	}{
+		{
+			vals:    []int64{0, 1, 5, 5},
+			want:    Segments{Segment{0, 1}, Segment{5, 5}},
+			wanterr: false,
+		},
+		{
+			vals:    nil,
+			want:    nil,
+			wanterr: false,
+		},
+		{
+			vals:    []int64{0, 1, 5},
+			want:    nil,
+			wanterr: true,
+		},
+		{
+			vals:    []int64{0, 1, 5, 4},
+			want:    nil,
+			wanterr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		got, goterr := Unflatten(test.vals)
+		if !reflect.DeepEqual(got, test.want) || (goterr != nil) != test.wanterr {
+			t.Errorf("Unflatten(%v) = %s, should be %s; got error? %t, want error? %t",
+				test.vals, got, test.want, goterr != nil, test.wanterr)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  string
+	}{
+		{
+			input: Segments{Segment{1, 3}, Segment{-5, 8}},
+			want:  "start,end\n1,3\n-5,8\n",
+		},
+		{
+			input: nil,
+			want:  "start,end\n",
+		},
+	}
+
+	for _, test := range testCases {
+		var b strings.Builder
+		if err := test.input.WriteCSV(&b); err != nil || b.String() != test.want {
+			t.Errorf("%s.WriteCSV() = %q, %v, should be %q", test.input, b.String(), err, test.want)
+		}
+		if got, err := ReadCSV(strings.NewReader(b.String())); err != nil || !reflect.DeepEqual(got, test.input) {
+			t.Errorf("ReadCSV(%q) = %s, %v, should be %s", b.String(), got, err, test.input)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    Segments
+		wanterr string
+	}{
+		{
+			input: "1,3\n5, 8\n",
+			want:  Segments{Segment{1, 3}, Segment{5, 8}},
+		},
+		{
+			input: "start,end\n1,3\n",
+			want:  Segments{Segment{1, 3}},
+		},
+		{
+			input:   "start,end\n1,3\n4,2\n",
+			wanterr: "row 3: end < start: nil segment returned",
+		},
+		{
+			input:   "1,3\nx,4\n",
+			wanterr: `row 2: invalid start: strconv.ParseInt: parsing "x": invalid syntax`,
+		},
+		{
+			input:   "1,3\n1,2,3\n",
+			wanterr: "row 2: record on line 2: wrong number of fields",
+		},
+		{
+			input:   "1,3\nstart,end\n",
+			wanterr: `row 2: invalid start: strconv.ParseInt: parsing "start": invalid syntax`,
+		},
+	}
+
+	for _, test := range testCases {
+		got, goterr := ReadCSV(strings.NewReader(test.input))
+		goterrstr := ""
+		if goterr != nil {
+			goterrstr = goterr.Error()
+		}
+		if !reflect.DeepEqual(got, test.want) || goterrstr != test.wanterr {
+			t.Errorf("ReadCSV(%q) = %s, %q, should be %s, %q", test.input, got, goterrstr, test.want, test.wanterr)
+		}
+	}
+}
+
+func TestRangeList(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  string
+	}{
+		{
+			input: Segments{
+				Segment{1, 3},
+				Segment{5, 5},
+				Segment{8, 10},
+			},
+			want: "1-3,5,8-10",
+		},
+		{
+			input: Segments{
+				Segment{-5, -3},
+				Segment{-1, 2},
+			},
+			want: "-5--3,-1-2",
+		},
+		{
+			input: nil,
+			want:  "",
+		},
+	}
+
+	for _, test := range testCases {
+		got := test.input.RangeList()
+		if got != test.want {
+			t.Errorf("%s.RangeList() = %q, should be %q", test.input, got, test.want)
+		}
+		if back, err := ParseRangeList(got); err != nil || !reflect.DeepEqual(back, test.input) {
+			t.Errorf("ParseRangeList(%q) = %s, %v; should round-trip to %s", got, back, err, test.input)
+		}
+	}
+}
+
+func TestParseRangeList(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    Segments
+		wanterr bool
+	}{
+		{
+			input:   " 1 - 3, 5 ,8-10 ",
+			want:    Segments{Segment{1, 3}, Segment{5, 5}, Segment{8, 10}},
+			wanterr: false,
+		},
+		{
+			input:   "",
+			want:    nil,
+			wanterr: false,
+		},
+		{
+			input:   "3-1",
+			want:    nil,
+			wanterr: true,
+		},
+		{
+			input:   "5-8,1-3",
+			want:    nil,
+			wanterr: true,
+		},
+		{
+			input:   "1-3,3-5",
+			want:    nil,
+			wanterr: true,
+		},
+		{
+			input:   "1-3,x",
+			want:    nil,
+			wanterr: true,
+		},
+		{
+			input:   "1-3,,5",
+			want:    nil,
+			wanterr: true,
+		},
+	}
+
+	for _, test := range testCases {
+		got, goterr := ParseRangeList(test.input)
+		if !reflect.DeepEqual(got, test.want) || (goterr != nil) != test.wanterr {
+			t.Errorf("ParseRangeList(%q) = %s, should be %s; got error? %t (%v), want error? %t",
+				test.input, got, test.want, goterr != nil, goterr, test.wanterr)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    Segments
+		wanterr string
+	}{
+		{
+			input: "11-13,20-25,30",
+			want:  Segments{Segment{11, 13}, Segment{20, 25}, Segment{30, 30}},
+		},
+		{
+			input: " 20 - 25 , 11-13,-5--3, 12 ",
+			want:  Segments{Segment{20, 25}, Segment{11, 13}, Segment{-5, -3}, Segment{12, 12}},
+		},
+		{
+			input: " ",
+			want:  nil,
+		},
+		{
+			input:   "11-13,25-20",
+			wanterr: `range "25-20": end < start: nil segment returned`,
+		},
+		{
+			input:   "11-13,x",
+			wanterr: `range "x": invalid start: strconv.ParseInt: parsing "x": invalid syntax`,
+		},
+	}
+
+	for _, test := range testCases {
+		got, goterr := Parse(test.input)
+		goterrstr := ""
+		if goterr != nil {
+			goterrstr = goterr.Error()
+		}
+		if !reflect.DeepEqual(got, test.want) || goterrstr != test.wanterr {
+			t.Errorf("Parse(%q) = %s, %q, should be %s, %q", test.input, got, goterrstr, test.want, test.wanterr)
+		}
+		if goterr == nil {
+			if back, err := Parse(got.CompactString()); err != nil || !reflect.DeepEqual(back, got) {
+				t.Errorf("Parse(%q) = %s, %v, should be %s", got.CompactString(), back, err, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2018, Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segment
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//////// CORE TYPES ////////
+
+// Number is the set of endpoint types supported by Interval.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Interval is a generic "start, end" line segment structure, with endpoints of
+// any Number type, such as float64 timestamps or time.Duration offsets.
+// It provides the core operations of Segment, which remains the int64 API.
+// The set operations of both types share one implementation, so they always agree.
+type Interval[T Number] struct {
+	start, end T
+}
+
+// Intervals is a slice of type Interval objects.
+type Intervals[T Number] []Interval[T]
+
+//////// PRINT AS STRING ////////
+
+// String returns the values of an interval in a string.
+func (s Interval[T]) String() string {
+	return fmt.Sprintf("[start: %v, end: %v]", s.start, s.end)
+}
+
+// String returns the values of an array of Intervals in a string.
+func (ss Intervals[T]) String() string {
+	var output []string
+	for _, s := range ss {
+		output = append(output, s.String())
+	}
+	return strings.Join(output, ", ")
+}
+
+//////// CREATE INTERVAL VALUES ////////
+
+// NewInterval creates an Interval struct from a start and an end. If end < start,
+// the Interval is not well-defined, so an error is returned, and the output interval is empty.
+func NewInterval[T Number](start, end T) (Interval[T], error) {
+	if end < start {
+		return Interval[T]{}, fmt.Errorf("end < start: nil interval returned")
+	}
+	return Interval[T]{start, end}, nil
+}
+
+//////// EXTRACT INTERVAL VALUES/CHARACTERISTICS ////////
+
+// Start returns the start of an interval.
+func (s Interval[T]) Start() T {
+	return s.start
+}
+
+// End returns the end of an interval.
+func (s Interval[T]) End() T {
+	return s.end
+}
+
+// Delta returns the length of the interval.
+func (s Interval[T]) Delta() T {
+	return s.end - s.start
+}
+
+// IsDeltaPositive reports whether an interval has positive delta.
+func (s Interval[T]) IsDeltaPositive() bool {
+	return s.end > s.start
+}
+
+// IsSubInterval reports whether interval s is a sub-interval of interval t.
+func (s Interval[T]) IsSubInterval(t Interval[T]) bool {
+	return s.start >= t.start && s.end <= t.end
+}
+
+//////// SET OPERATIONS ////////
+
+// RemoveIntervalOverlaps takes out overlapping areas in a slice of intervals,
+// like RemoveOverlaps. The output is sorted by start.
+func RemoveIntervalOverlaps[T Number](ss Intervals[T]) Intervals[T] {
+	return removeOverlaps[T](ss)
+}
+
+// UnionIntervals finds the overlap between slices of intervals, like Union.
+func UnionIntervals[T Number](ss ...Intervals[T]) Intervals[T] {
+	return union[T](ss...)
+}
+
+// IntersectIntervals returns the intervals where all the slices of intervals overlap, like Intersect.
+func IntersectIntervals[T Number](ss ...Intervals[T]) Intervals[T] {
+	return intersect[T](ss...)
+}
+
+// ComplementIntervals returns all intervals in the superset that are not in ss, like Complement.
+func ComplementIntervals[T Number](superset Interval[T], ss Intervals[T]) Intervals[T] {
+	return complement[T](superset, ss)
+}
+
+//////// SHARED SET OPERATIONS ////////
+
+// The set operations of both Segment and Interval are implemented once, below,
+// over any type whose underlying structure is endpoints.
+
+// endpoints is the structure underlying both Segment, with T int64, and Interval[T].
+type endpoints[T Number] struct {
+	start, end T
+}
+
+// withEndpoints is satisfied by Segment, with T int64, and by Interval[T].
+type withEndpoints[T Number] interface {
+	~struct{ start, end T }
+}
+
+// sortByStart sorts ss in place by start.
+func sortByStart[T Number, E withEndpoints[T]](ss []E) {
+	sort.Slice(ss, func(i, j int) bool { return endpoints[T](ss[i]).start < endpoints[T](ss[j]).start })
+}
+
+// mergeSorted appends to dst the elements of ss, which must be sorted by start,
+// with overlapping and touching elements merged. dst may be ss[:0] to merge in
+// place: merged elements are written at or before the index being read, so the
+// input is never overwritten before it is read.
+func mergeSorted[T Number, E withEndpoints[T], S ~[]E](dst, ss S) S {
+	for _, e := range ss {
+		s, n := endpoints[T](e), len(dst)
+		// Do we need to start a new element?
+		// Checking for an empty output rather than comparing against a sentinel
+		// means that elements starting at the smallest value of T are kept.
+		if n == 0 || endpoints[T](dst[n-1]).end < s.start {
+			dst = append(dst, e)
+		} else if last := endpoints[T](dst[n-1]); last.end < s.end {
+			// Do we need to update the end of the existing last element?
+			last.end = s.end
+			dst[n-1] = E(last)
+		}
+	}
+	return dst
+}
+
+// removeOverlaps sorts a copy of ss by start, and merges it, as per RemoveOverlaps.
+func removeOverlaps[T Number, E withEndpoints[T], S ~[]E](ss S) S {
+	// In order to not sort in place, we make a copy of ss.
+	sorted := append(S{}, ss...)
+	sortByStart[T](sorted)
+	return mergeSorted[T](nil, sorted)
+}
+
+// union merges all the slices, as per Union.
+func union[T Number, E withEndpoints[T], S ~[]E](ss ...S) S {
+	var tt S
+	for _, s := range ss {
+		tt = append(tt, s...)
+	}
+	return removeOverlaps[T](tt)
+}
+
+// simpleIntersection returns the intersection of s and t, and whether there is
+// one, as per SimpleIntersection.
+func simpleIntersection[T Number, E withEndpoints[T]](s, t E) (E, bool) {
+	a, b := endpoints[T](s), endpoints[T](t)
+	if lo, hi := max(a.start, b.start), min(a.end, b.end); lo <= hi {
+		return E(endpoints[T]{lo, hi}), true
+	}
+	return E{}, false
+}
+
+// compare3Way performs one step of a two-pointer sweep, as per Segment.Compare3Way.
+func compare3Way[T Number, E withEndpoints[T]](s, t E) (overlap E, hasOverlap bool, advance int) {
+	overlap, hasOverlap = simpleIntersection[T](s, t)
+	if sEnd, tEnd := endpoints[T](s).end, endpoints[T](t).end; sEnd == tEnd {
+		// If the two elements have the same end, no remaining elements can
+		// intersect with either element, so advance both iterators.
+		advance = 0
+	} else if sEnd > tEnd {
+		// If s ends after t, no other remaining element from the first slice can
+		// intersect with t, so advance the second slice to its next element.
+		advance = 1
+	} else {
+		// t must end after s, so see the above comment.
+		advance = -1
+	}
+	return overlap, hasOverlap, advance
+}
+
+// intersect returns the elements where all the slices overlap, as per Intersect.
+func intersect[T Number, E withEndpoints[T], S ~[]E](ss ...S) S {
+	if len(ss) == 0 {
+		return nil
+	}
+	output := removeOverlaps[T](ss[0])
+	for _, tt := range ss[1:] {
+		output = intersectSorted[T](output, removeOverlaps[T](tt))
+	}
+	return output
+}
+
+// intersectSorted returns the elements where two slices overlap, with a
+// two-pointer sweep. Both slices must be as returned by removeOverlaps.
+func intersectSorted[T Number, E withEndpoints[T], S ~[]E](ss, tt S) S {
+	var output S
+	for i, j := 0, 0; i < len(ss) && j < len(tt); {
+		overlap, ok, advance := compare3Way[T](ss[i], tt[j])
+		if ok {
+			output = append(output, overlap)
+		}
+		if advance <= 0 {
+			i++
+		}
+		if advance >= 0 {
+			j++
+		}
+	}
+	return output
+}
+
+// complement returns the parts of superset not covered by ss, as per Complement.
+func complement[T Number, E withEndpoints[T], S ~[]E](superset E, ss S) S {
+	sup := endpoints[T](superset)
+	// If the superset is not well-defined, return the empty slice.
+	if sup.end <= sup.start {
+		return nil
+	}
+	output := S{superset}
+
+	for _, e := range removeOverlaps[T](ss) {
+		s := endpoints[T](e)
+		// If the superset is a subset of s, return the nil slice.
+		if s.start <= sup.start && sup.end <= s.end {
+			return nil
+		}
+		// If there is no intersection between the superset and s, continue.
+		if _, ok := simpleIntersection[T](superset, e); !ok {
+			continue
+		}
+		n := len(output) - 1
+		last := endpoints[T](output[n])
+		if s.start <= sup.start {
+			last.start = s.end
+			output[n] = E(last)
+			continue
+		}
+		if s.end >= sup.end {
+			last.end = s.start
+			output[n] = E(last)
+			continue
+		}
+		// Now, we know s must be a strict sub-element of superset.
+		// We also are guaranteed that the element to append has positive delta.
+		last.end = s.start
+		output[n] = E(last)
+		output = append(output, E(endpoints[T]{s.end, sup.end}))
+	}
+	return output
+}
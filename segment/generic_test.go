@@ -0,0 +1,154 @@
+// Copyright (c) 2018, Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segment
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Please keep the order of test functions the same as
+// the order of methods/functions in generic.go.
+
+func TestIntervalString(t *testing.T) {
+	if got, want := (Interval[float64]{1.5, 2}).String(), "[start: 1.5, end: 2]"; got != want {
+		t.Errorf("s.String() = %s, should be %s", got, want)
+	}
+	if got, want := (Intervals[time.Duration]{{time.Second, time.Minute}}).String(), "[start: 1s, end: 1m0s]"; got != want {
+		t.Errorf("s.String() = %s, should be %s", got, want)
+	}
+}
+
+func TestNewInterval(t *testing.T) {
+	if got, err := NewInterval(0.5, 1.5); err != nil || got != (Interval[float64]{0.5, 1.5}) {
+		t.Errorf("NewInterval(0.5, 1.5) = %s, %v, should be %s", got, err, Interval[float64]{0.5, 1.5})
+	}
+	if got, err := NewInterval(time.Second, time.Millisecond); err == nil || got != (Interval[time.Duration]{}) {
+		t.Errorf("NewInterval(1s, 1ms) = %s, %v, should be empty with an error", got, err)
+	}
+}
+
+func TestIntervalDelta(t *testing.T) {
+	if got, want := (Interval[float64]{0.25, 1}).Delta(), 0.75; got != want {
+		t.Errorf("Delta() = %v, should be %v", got, want)
+	}
+	if got, want := (Interval[time.Duration]{time.Second, time.Minute}).Delta(), 59*time.Second; got != want {
+		t.Errorf("Delta() = %v, should be %v", got, want)
+	}
+}
+
+func TestRemoveIntervalOverlaps(t *testing.T) {
+	testCases := []struct {
+		input, want Intervals[float64]
+	}{
+		{
+			input: Intervals[float64]{{2, 3}, {1, 2}, {4, 5}},
+			want:  Intervals[float64]{{1, 3}, {4, 5}},
+		},
+		{
+			input: Intervals[float64]{{0.5, 1}, {-1, 5}, {4, 6.5}},
+			want:  Intervals[float64]{{-1, 6.5}},
+		},
+		{
+			input: Intervals[float64]{{math.Inf(-1), 3}, {4, 5}},
+			want:  Intervals[float64]{{math.Inf(-1), 3}, {4, 5}},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := RemoveIntervalOverlaps(test.input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("RemoveIntervalOverlaps(%s) is %s, should be %s", test.input, got, test.want)
+		}
+	}
+
+	// As in RemoveOverlaps, an interval starting at math.MinInt64 is kept.
+	input := Intervals[int64]{{math.MinInt64, 3}, {4, 5}}
+	if got := RemoveIntervalOverlaps(input); !reflect.DeepEqual(got, input) {
+		t.Errorf("RemoveIntervalOverlaps(%s) is %s, should be %s", input, got, input)
+	}
+}
+
+func TestUnionIntervals(t *testing.T) {
+	x := Intervals[time.Duration]{{time.Second, 3 * time.Second}}
+	y := Intervals[time.Duration]{{2 * time.Second, 4 * time.Second}, {10 * time.Second, 11 * time.Second}}
+	want := Intervals[time.Duration]{{time.Second, 4 * time.Second}, {10 * time.Second, 11 * time.Second}}
+	if got := UnionIntervals(x, y); !reflect.DeepEqual(got, want) {
+		t.Errorf("UnionIntervals(%s, %s) = %s, want %s", x, y, got, want)
+	}
+	if got := UnionIntervals[float64](); got != nil {
+		t.Errorf("UnionIntervals() = %s, want nil", got)
+	}
+}
+
+func TestIntersectIntervals(t *testing.T) {
+	x := Intervals[float64]{{1, 5}, {2, 10}, {12, 16}}
+	y := Intervals[float64]{{3, 7.5}, {16, 17}}
+	want := Intervals[float64]{{3, 7.5}, {16, 16}}
+	if got := IntersectIntervals(x, y); !reflect.DeepEqual(got, want) {
+		t.Errorf("IntersectIntervals(%s, %s) = %s, want %s", x, y, got, want)
+	}
+
+	// As with Intersect, IntersectIntervals(x, y, z) is IntersectIntervals(IntersectIntervals(x, y), z).
+	z := Intervals[float64]{{4, 5}, {15, 20}}
+	want = Intervals[float64]{{4, 5}, {16, 16}}
+	if got := IntersectIntervals(x, y, z); !reflect.DeepEqual(got, want) {
+		t.Errorf("IntersectIntervals(%s, %s, %s) = %s, want %s", x, y, z, got, want)
+	}
+	if got := IntersectIntervals[float64](); got != nil {
+		t.Errorf("IntersectIntervals() = %s, want nil", got)
+	}
+}
+
+func TestComplementIntervals(t *testing.T) {
+	ss := Intervals[float64]{{1, 3}, {0, 2}, {4, 6}}
+	superset := Interval[float64]{-10, 5.5}
+	want := Intervals[float64]{{-10, 0}, {3, 4}}
+	if got := ComplementIntervals(superset, ss); !reflect.DeepEqual(got, want) {
+		t.Errorf("ComplementIntervals(%s, %s) = %s, want %s", superset, ss, got, want)
+	}
+	if got := ComplementIntervals(Interval[float64]{1, 1}, ss); got != nil {
+		t.Errorf("ComplementIntervals(%s, %s) = %s, want nil", Interval[float64]{1, 1}, ss, got)
+	}
+}
+
+// TestIntervalsMatchSegments checks that the int64 instantiation of the generic
+// operations matches the Segment API on random inputs.
+func TestIntervalsMatchSegments(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	toIntervals := func(ss Segments) Intervals[int64] {
+		var output Intervals[int64]
+		for _, s := range ss {
+			output = append(output, Interval[int64]{s.start, s.end})
+		}
+		return output
+	}
+	for n := 0; n < 1000; n++ {
+		x, y := randomSegments(r, 5, 40, 10), randomSegments(r, 5, 40, 10)
+		xi, yi := toIntervals(x), toIntervals(y)
+		if got, want := UnionIntervals(xi, yi), toIntervals(Union(x, y)); !reflect.DeepEqual(got, want) {
+			t.Errorf("UnionIntervals(%s, %s) = %s, want %s", xi, yi, got, want)
+		}
+		if got, want := IntersectIntervals(xi, yi), toIntervals(Intersect(x, y)); !reflect.DeepEqual(got, want) {
+			t.Errorf("IntersectIntervals(%s, %s) = %s, want %s", xi, yi, got, want)
+		}
+		superset := Segment{-10, 10}
+		if got, want := ComplementIntervals(Interval[int64]{-10, 10}, yi), toIntervals(Complement(superset, y)); !reflect.DeepEqual(got, want) {
+			t.Errorf("ComplementIntervals(%s, %s) = %s, want %s", superset, yi, got, want)
+		}
+	}
+}
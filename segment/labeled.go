@@ -0,0 +1,119 @@
+// Copyright (c) 2018, Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segment
+
+import "slices"
+
+//////// CORE TYPES ////////
+
+// Labeled is a Segment carrying a label of any type.
+type Labeled[T any] struct {
+	Seg   Segment
+	Label T
+}
+
+// LabeledSet is a slice of type Labeled objects.
+type LabeledSet[T any] []Labeled[T]
+
+//////// SPLIT SEGMENTS ////////
+
+// SplitAt splits the segment of l at the given points, and returns the pieces in
+// increasing order, each carrying the label of l.
+// Only points strictly inside the segment cut it; other points and duplicates are ignored.
+func (l Labeled[T]) SplitAt(points []int64) LabeledSet[T] {
+	var cuts []int64
+	for _, p := range points {
+		if l.Seg.start < p && p < l.Seg.end {
+			cuts = append(cuts, p)
+		}
+	}
+	slices.Sort(cuts)
+	cuts = slices.Compact(cuts)
+
+	var output LabeledSet[T]
+	start := l.Seg.start
+	for _, p := range cuts {
+		output = append(output, Labeled[T]{Segment{start, p}, l.Label})
+		start = p
+	}
+	return append(output, Labeled[T]{Segment{start, l.Seg.end}, l.Label})
+}
+
+//////// SET OPERATIONS ////////
+
+// UnionLabeled returns the union of several labeled sources, keeping track of
+// which sources cover each part of the union.
+// The line is partitioned at every endpoint of every source, and each piece
+// covered by at least one source is returned with the labels of all the
+// sources covering it. The output is sorted by start and does not overlap.
+// The labels of a piece are in no particular order, since they come from a map;
+// callers needing a stable order should sort them.
+// Segments within a source are merged as per RemoveOverlaps first, and
+// zero-length pieces are not returned since they cover nothing.
+func UnionLabeled[T comparable](sets map[T]Segments) LabeledSet[[]T] {
+	var labels []T
+	for l := range sets {
+		labels = append(labels, l)
+	}
+
+	normalized := make([]Segments, len(labels))
+	var endpoints []int64
+	for i, l := range labels {
+		normalized[i] = RemoveOverlaps(sets[l])
+		for _, s := range normalized[i] {
+			endpoints = append(endpoints, s.start, s.end)
+		}
+	}
+	slices.Sort(endpoints)
+	endpoints = slices.Compact(endpoints)
+
+	var output LabeledSet[[]T]
+	next := make([]int, len(labels))
+	for k := 1; k < len(endpoints); k++ {
+		piece := Segment{endpoints[k-1], endpoints[k]}
+		var covering []T
+		for i, ss := range normalized {
+			// Skip the segments of this source that end before the piece.
+			for next[i] < len(ss) && ss[next[i]].end <= piece.start {
+				next[i]++
+			}
+			if next[i] < len(ss) && piece.IsSubSegment(ss[next[i]]) {
+				covering = append(covering, labels[i])
+			}
+		}
+		if covering != nil {
+			output = append(output, Labeled[[]T]{piece, covering})
+		}
+	}
+	return output
+}
+
+// FillSmallGaps returns the segments of RemoveOverlaps(ss), with filler segments
+// inserted into the gaps of length at most maxGap between them. The output is
+// sorted by start, and each piece is labeled true if it is a filler segment,
+// and false if it comes from ss. Unlike merging, this keeps interpolated pieces
+// distinguishable from real ones.
+func FillSmallGaps(ss Segments, maxGap int64) LabeledSet[bool] {
+	var output LabeledSet[bool]
+	for i, s := range RemoveOverlaps(ss) {
+		if n := len(output); i > 0 {
+			if gap := (Segment{output[n-1].Seg.end, s.start}); gap.Delta() <= maxGap {
+				output = append(output, Labeled[bool]{gap, true})
+			}
+		}
+		output = append(output, Labeled[bool]{s, false})
+	}
+	return output
+}
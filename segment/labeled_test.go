@@ -0,0 +1,150 @@
+// Copyright (c) 2018, Google Inc. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package segment
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// Please keep the order of test functions the same as
+// the order of methods/functions in labeled.go.
+
+func TestLabeledSplitAt(t *testing.T) {
+	testCases := []struct {
+		l      Labeled[string]
+		points []int64
+		want   LabeledSet[string]
+	}{
+		{
+			l:      Labeled[string]{Segment{0, 10}, "speech"},
+			points: []int64{7, 3, 7, 0, 12, -1},
+			want: LabeledSet[string]{
+				{Segment{0, 3}, "speech"},
+				{Segment{3, 7}, "speech"},
+				{Segment{7, 10}, "speech"},
+			},
+		},
+		{
+			l:      Labeled[string]{Segment{0, 10}, "music"},
+			points: nil,
+			want: LabeledSet[string]{
+				{Segment{0, 10}, "music"},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.l.SplitAt(test.points); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v.SplitAt(%v) = %v, want %v", test.l, test.points, got, test.want)
+		}
+	}
+}
+
+func TestUnionLabeled(t *testing.T) {
+	testCases := []struct {
+		description string
+		sets        map[string]Segments
+		want        LabeledSet[[]string]
+	}{
+		{
+			description: "two overlapping sources",
+			sets: map[string]Segments{
+				"b": {Segment{5, 15}},
+				"a": {Segment{0, 10}, Segment{20, 30}},
+			},
+			want: LabeledSet[[]string]{
+				{Segment{0, 5}, []string{"a"}},
+				{Segment{5, 10}, []string{"a", "b"}},
+				{Segment{10, 15}, []string{"b"}},
+				{Segment{20, 30}, []string{"a"}},
+			},
+		},
+		{
+			description: "overlaps within a source are merged",
+			sets: map[string]Segments{
+				"a": {Segment{0, 5}, Segment{3, 10}},
+				"b": {Segment{10, 12}},
+			},
+			want: LabeledSet[[]string]{
+				{Segment{0, 10}, []string{"a"}},
+				{Segment{10, 12}, []string{"b"}},
+			},
+		},
+		{
+			description: "no sources",
+			sets:        nil,
+			want:        nil,
+		},
+	}
+
+	for _, test := range testCases {
+		got := UnionLabeled(test.sets)
+		// The labels of a piece are in no particular order.
+		for _, l := range got {
+			slices.Sort(l.Label)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: UnionLabeled(%v) = %v, want %v", test.description, test.sets, got, test.want)
+		}
+	}
+}
+
+func TestFillSmallGaps(t *testing.T) {
+	testCases := []struct {
+		input  Segments
+		maxGap int64
+		want   LabeledSet[bool]
+	}{
+		{
+			input: Segments{
+				Segment{20, 30},
+				Segment{0, 10},
+				Segment{12, 15},
+			},
+			maxGap: 3,
+			want: LabeledSet[bool]{
+				{Segment{0, 10}, false},
+				{Segment{10, 12}, true},
+				{Segment{12, 15}, false},
+				{Segment{20, 30}, false},
+			},
+		},
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{20, 30},
+			},
+			maxGap: 10,
+			want: LabeledSet[bool]{
+				{Segment{0, 10}, false},
+				{Segment{10, 20}, true},
+				{Segment{20, 30}, false},
+			},
+		},
+		{
+			input:  nil,
+			maxGap: 10,
+			want:   nil,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := FillSmallGaps(test.input, test.maxGap); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("FillSmallGaps(%s, %d) = %v, want %v", test.input, test.maxGap, got, test.want)
+		}
+	}
+}
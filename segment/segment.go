@@ -13,13 +13,21 @@
 // limitations under the License.
 
 // Package segment provides mathematical operators on generic start-end line segments.
+//
+// Segments are closed: a segment contains both its start and its end, so
+// segments which only touch, such as {0, 5} and {5, 10}, intersect at a point
+// and are merged by RemoveOverlaps. Functions with a HalfOpen suffix instead
+// treat segments as half-open [start, end) intervals, where end is excluded.
 package segment
 
 import (
 	"fmt"
 	"math"
+	"math/rand"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
 //////// CORE TYPES ////////
@@ -48,6 +56,31 @@ func (ss Segments) String() string {
 	return strings.Join(output, ", ")
 }
 
+// CompactString returns the values of a segment in a compact string,
+// such as "11-13", or "11" for a point segment.
+func (s Segment) CompactString() string {
+	if s.start == s.end {
+		return strconv.FormatInt(s.start, 10)
+	}
+	return fmt.Sprintf("%d-%d", s.start, s.end)
+}
+
+// CompactString returns the values of an array of Segments in a compact string,
+// such as "11-13,20-25,30".
+func (ss Segments) CompactString() string {
+	var output []string
+	for _, s := range ss {
+		output = append(output, s.CompactString())
+	}
+	return strings.Join(output, ",")
+}
+
+// DurationString returns the length of a segment as a duration, where a unit
+// of the segment lasts unit, such as "2ms" or "1.5s", as per time.Duration.
+func (s Segment) DurationString(unit time.Duration) string {
+	return (time.Duration(s.Delta()) * unit).String()
+}
+
 //////// CREATE/UPDATE SEGMENT VALUES ////////
 
 // New creates a Segment struct from a start and an end., If end < start,
@@ -59,6 +92,16 @@ func New(start, end int64) (Segment, error) {
 	return Segment{start, end}, nil
 }
 
+// NewStrict creates a Segment struct from a start and an end, like New, but
+// additionally rejects zero-length segments: if end <= start, an error is returned,
+// and the output segment is nil.
+func NewStrict(start, end int64) (Segment, error) {
+	if end == start {
+		return Segment{}, fmt.Errorf("end == start: nil segment returned")
+	}
+	return New(start, end)
+}
+
 // Update sets the values of a Segment in place.
 // The values are only set if start <= end. If not, an error is returned.
 func (s *Segment) Update(start, end int64) error {
@@ -118,6 +161,195 @@ func (ss Segments) LinearTransform(a, b float64) error {
 	return nil
 }
 
+// Shift translates a segment by delta in place. It is equivalent to
+// LinearTransform(1, float64(delta)), but uses exact integer arithmetic.
+func (s *Segment) Shift(delta int64) {
+	s.start += delta
+	s.end += delta
+}
+
+// Shift translates every segment of a slice by delta in place, using exact integer arithmetic.
+func (ss Segments) Shift(delta int64) {
+	for i := range ss {
+		ss[i].Shift(delta)
+	}
+}
+
+// Scale multiplies the start and end of a segment by factor in place.
+// It is equivalent to LinearTransform(factor, 0), so a negative factor returns
+// an error and the output is rounded in the same way.
+func (s *Segment) Scale(factor float64) error {
+	return s.LinearTransform(factor, 0)
+}
+
+// Scale multiplies the starts and ends of a slice of segments by factor in place.
+// It is equivalent to LinearTransform(factor, 0), so a negative factor returns
+// an error and no segment is scaled.
+func (ss Segments) Scale(factor float64) error {
+	return ss.LinearTransform(factor, 0)
+}
+
+// Dilate returns the segment extended outward by amount at both ends.
+// If amount < 0, the segment is returned unchanged; use Erode to shrink it.
+func (s Segment) Dilate(amount int64) Segment {
+	if amount < 0 {
+		return s
+	}
+	return Segment{s.start - amount, s.end + amount}
+}
+
+// Dilate extends every segment of ss outward by amount at both ends, and
+// returns the result with overlaps removed, as per RemoveOverlaps, so gaps of
+// length up to 2*amount are closed. The input is not modified.
+// If amount < 0, overlaps are removed but the segments are not dilated.
+func (ss Segments) Dilate(amount int64) Segments {
+	var dilated Segments
+	for _, s := range ss {
+		dilated = append(dilated, s.Dilate(amount))
+	}
+	return RemoveOverlaps(dilated)
+}
+
+// Erode returns the segment shrunk inward by amount at both ends.
+// If the segment is shorter than 2*amount, shrinking would give end < start,
+// so false is returned and the output segment is nil. A segment of length
+// exactly 2*amount erodes to a point.
+// If amount < 0, the segment is returned unchanged.
+func (s Segment) Erode(amount int64) (Segment, bool) {
+	if amount < 0 {
+		return s, true
+	}
+	// This is s.Delta() < 2*amount, without overflowing for a large amount
+	// or a segment longer than math.MaxInt64.
+	if uint64(amount) > uint64(s.end-s.start)/2 {
+		return Segment{}, false
+	}
+	return Segment{s.start + amount, s.end - amount}, true
+}
+
+// Erode shrinks every segment of ss inward by amount at both ends, and drops
+// the segments which collapse, as per Segment.Erode. The output is in input
+// order, and the input is not modified.
+func (ss Segments) Erode(amount int64) Segments {
+	var output Segments
+	for _, s := range ss {
+		if eroded, ok := s.Erode(amount); ok {
+			output = append(output, eroded)
+		}
+	}
+	return output
+}
+
+// Mirror returns the segment reflected about pivot, for example to reverse a
+// time axis. The endpoints are swapped so the result stays well-defined:
+// the new start is 2*pivot - end, and the new end is 2*pivot - start.
+func (s Segment) Mirror(pivot int64) Segment {
+	return Segment{2*pivot - s.end, 2*pivot - s.start}
+}
+
+// Mirror returns every segment of ss reflected about pivot, as per
+// Segment.Mirror, sorted by start, then by end. The input is not modified.
+func (ss Segments) Mirror(pivot int64) Segments {
+	var output Segments
+	for _, s := range ss {
+		output = append(output, s.Mirror(pivot))
+	}
+	output.Sort()
+	return output
+}
+
+// Rebase returns a copy of ss shifted so that its smallest start is 0, and the
+// offset subtracted from every segment, which is that smallest start; shifting
+// the copy by the offset gives ss back. Rebasing two sets aligns them before
+// comparing them, for example with Jaccard. It uses exact integer arithmetic, as
+// Shift does. If ss is empty, it returns nil and 0. The input is not modified.
+func (ss Segments) Rebase() (Segments, int64) {
+	bounds, ok := ss.Bounds()
+	if !ok {
+		return nil, 0
+	}
+	output := ss.Clone()
+	output.Shift(-bounds.start)
+	return output, bounds.start
+}
+
+// ProjectWith maps every endpoint of ss through mapEndpoint, and returns the
+// resulting segments with overlaps removed, as per RemoveOverlaps.
+// It generalizes LinearTransform to arbitrary warps of the line.
+// mapEndpoint should be monotonic non-decreasing; if it is not, some segments may
+// come out with end < start, and such segments are discarded. The input is not modified.
+func (ss Segments) ProjectWith(mapEndpoint func(int64) int64) Segments {
+	var projected Segments
+	for _, s := range ss {
+		if start, end := mapEndpoint(s.start), mapEndpoint(s.end); start <= end {
+			projected = append(projected, Segment{start, end})
+		}
+	}
+	return RemoveOverlaps(projected)
+}
+
+// SnapEndpoints snaps near-coincident endpoints of ss to shared values.
+// Endpoints are sorted and grouped into clusters spanning at most tol, and every
+// endpoint of a cluster is replaced with the mean of the cluster, rounded.
+// Snapping preserves the order of endpoints, so no segment ends up with end < start,
+// though short segments may collapse to points. The output is in input order,
+// and overlaps are not removed. A negative tol is treated as 0.
+func SnapEndpoints(ss Segments, tol int64) Segments {
+	tol = max(tol, 0)
+	endpoints := append(ss.Starts(), ss.Ends()...)
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i] < endpoints[j] })
+
+	snapped := make(map[int64]int64)
+	for i := 0; i < len(endpoints); {
+		first := endpoints[i]
+		var sum float64
+		j := i
+		for ; j < len(endpoints) && endpoints[j]-first <= tol; j++ {
+			sum += float64(endpoints[j])
+		}
+		last := endpoints[j-1]
+		mean := int64(math.Round(sum / float64(j-i)))
+		// Guard against floating point error taking the mean outside the cluster.
+		mean = min(max(mean, first), last)
+		for ; i < j; i++ {
+			snapped[endpoints[i]] = mean
+		}
+	}
+
+	var output Segments
+	for _, s := range ss {
+		output = append(output, Segment{snapped[s.start], snapped[s.end]})
+	}
+	return output
+}
+
+// UnitInterval is a segment expressed as fractions of a reference segment.
+type UnitInterval struct {
+	Start, End float64
+}
+
+// NormalizeToUnit maps every segment of ss to fractions of the reference
+// segment, where reference.start maps to 0 and reference.end maps to 1.
+// Segments are first clipped to the reference, and segments which do not
+// intersect the reference are dropped; the output is otherwise in input order.
+// If the reference has zero length, an error is returned.
+func (ss Segments) NormalizeToUnit(reference Segment) ([]UnitInterval, error) {
+	if !reference.IsDeltaPositive() {
+		return nil, fmt.Errorf("reference has zero length: segments not normalized")
+	}
+	length := float64(reference.Delta())
+	var output []UnitInterval
+	for _, s := range ss {
+		if clipped, ok := SimpleIntersection(s, reference); ok {
+			output = append(output, UnitInterval{
+				Start: float64(clipped.start-reference.start) / length,
+				End:   float64(clipped.end-reference.start) / length,
+			})
+		}
+	}
+	return output, nil
+}
+
 //////// EXTRACT SEGMENT VALUES/CHARACTERISTICS ////////
 
 // Start returns the start of a segment.
@@ -148,16 +380,122 @@ func (ss Segments) Ends() []int64 {
 	return output
 }
 
+// Bounds returns the smallest segment containing every segment of ss, from the
+// smallest start to the largest end, in O(n) without sorting. It is a natural
+// superset for Complement. If ss is empty, false is returned and the output segment is nil.
+func (ss Segments) Bounds() (Segment, bool) {
+	if len(ss) == 0 {
+		return Segment{}, false
+	}
+	bounds := ss[0]
+	for _, s := range ss[1:] {
+		bounds.start, bounds.end = min(bounds.start, s.start), max(bounds.end, s.end)
+	}
+	return bounds, true
+}
+
+// Center returns the midpoint of a segment, rounded down.
+// It does not overflow, even for segments spanning most of the int64 range.
+func (s Segment) Center() int64 {
+	return (s.start & s.end) + (s.start^s.end)>>1
+}
+
+// Centers returns the centers of Segments in a slice, in the same order.
+func (ss Segments) Centers() []int64 {
+	var output []int64
+	for _, s := range ss {
+		output = append(output, s.Center())
+	}
+	return output
+}
+
+// CenterSegments returns point segments at the centers of Segments in a slice, in the same order.
+func (ss Segments) CenterSegments() Segments {
+	var output Segments
+	for _, s := range ss {
+		c := s.Center()
+		output = append(output, Segment{c, c})
+	}
+	return output
+}
+
+// Points returns the points start, start+step, start+2*step, ... up to and
+// including end if it is reached. If step <= 0, nil is returned.
+func (s Segment) Points(step int64) []int64 {
+	var output []int64
+	s.Each(step, func(p int64) bool {
+		output = append(output, p)
+		return true
+	})
+	return output
+}
+
+// Each calls fn on the points start, start+step, start+2*step, ... up to and
+// including end if it is reached, stopping early if fn returns false.
+// If step <= 0, fn is never called.
+func (s Segment) Each(step int64, fn func(p int64) bool) {
+	if step <= 0 {
+		return
+	}
+	for p := s.start; p <= s.end; p += step {
+		// Stop before p+step can overflow past end. The remaining length is
+		// compared as a uint64, which is exact since p <= end.
+		if !fn(p) || uint64(s.end-p) < uint64(step) {
+			return
+		}
+	}
+}
+
 // Delta returns the length of the segment.
 func (s Segment) Delta() int64 {
 	return s.end - s.start
 }
 
+// DeltaChecked returns the length of the segment, like Delta, but returns an
+// error if end - start overflows int64, as it does for segments spanning more
+// than half of the int64 range, such as {math.MinInt64, 0}.
+func (s Segment) DeltaChecked() (int64, error) {
+	d := s.end - s.start
+	// The true difference has the sign of end - start, so a wrapped one has the other sign.
+	if (s.end >= s.start) != (d >= 0) {
+		return 0, fmt.Errorf("end - start overflows int64: no delta returned")
+	}
+	return d, nil
+}
+
 // IsDeltaPositive reports whether a segment has positive delta.
 func (s Segment) IsDeltaPositive() bool {
 	return s.Delta() > 0
 }
 
+// AsTimeRange returns the wall-clock times of the start and end of a segment,
+// where point 0 is at epoch and a unit of the segment lasts unit.
+func (s Segment) AsTimeRange(epoch time.Time, unit time.Duration) (time.Time, time.Time) {
+	return epoch.Add(time.Duration(s.start) * unit), epoch.Add(time.Duration(s.end) * unit)
+}
+
+// PointCount returns the number of integer points in the segment, endpoints included,
+// that is Delta() + 1. A count which does not fit in an int64, as for
+// {0, math.MaxInt64}, is capped at math.MaxInt64.
+func (s Segment) PointCount() int64 {
+	return capPointCount(pointCount(s))
+}
+
+// pointCount is PointCount as a uint64, which does not overflow unless s is
+// the whole int64 range, when it wraps to 0.
+func pointCount(s Segment) uint64 {
+	return uint64(s.end-s.start) + 1
+}
+
+// capPointCount converts a point count, as returned by pointCount, to an int64,
+// capped at math.MaxInt64. A count of 0 means 2^64 points.
+func capPointCount(count uint64) int64 {
+	if count == 0 || count > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(count)
+}
+
 // SumDeltas returns the sum of Deltas in an array of Segments.
 func (ss Segments) SumDeltas() int64 {
 	var output int64
@@ -167,6 +505,63 @@ func (ss Segments) SumDeltas() int64 {
 	return output
 }
 
+// CoveredLength returns the total length covered by an array of Segments.
+// Unlike SumDeltas, overlapping regions are only counted once.
+func (ss Segments) CoveredLength() int64 {
+	return RemoveOverlaps(ss).SumDeltas()
+}
+
+// TotalPointCount returns the number of distinct integer points covered by an array of Segments.
+// Overlaps are removed first, so points shared by several segments, including the endpoint
+// of touching segments such as {0, 2} and {2, 4}, are only counted once.
+// As with PointCount, a count which does not fit in an int64 is capped at math.MaxInt64.
+func (ss Segments) TotalPointCount() int64 {
+	normalized := RemoveOverlaps(ss)
+	if len(normalized) == 0 {
+		return 0
+	}
+	// As in SamplePoint, the uint64 sum only wraps to 0 for the whole int64 range.
+	var output uint64
+	for _, s := range normalized {
+		output += pointCount(s)
+	}
+	return capPointCount(output)
+}
+
+// SamplePoint returns an integer point drawn uniformly at random from r among
+// the points covered by ss, as counted by TotalPointCount, so that longer
+// segments are proportionally more likely. If ss is empty, false is returned.
+// Point counts are handled as uint64, so any valid input can be sampled,
+// up to the whole int64 range.
+func (ss Segments) SamplePoint(r *rand.Rand) (int64, bool) {
+	normalized := RemoveOverlaps(ss)
+	if len(normalized) == 0 {
+		return 0, false
+	}
+	// Disjoint segments cover at most 2^64 points, so total only wraps to 0
+	// when the whole int64 range is covered, in which case any k is valid.
+	var total uint64
+	for _, s := range normalized {
+		total += pointCount(s)
+	}
+	k := r.Uint64()
+	if total != 0 {
+		// Reject the draws below 2^64 % total, so that k % total is uniform.
+		for k < -total%total {
+			k = r.Uint64()
+		}
+		k %= total
+	}
+	last := len(normalized) - 1
+	for _, s := range normalized[:last] {
+		if k < pointCount(s) {
+			return s.start + int64(k), true
+		}
+		k -= pointCount(s)
+	}
+	return normalized[last].start + int64(k), true
+}
+
 // SumDeltasUpToPoint returns the sum of segments length below point.
 func SumDeltasUpToPoint(ss Segments, point int64) int64 {
 	var output int64
@@ -183,185 +578,1682 @@ func SumDeltasUpToPoint(ss Segments, point int64) int64 {
 	return output
 }
 
-// SegmentsWithPredicate returns a subset of segments that meet a predicate function.
-func SegmentsWithPredicate(ss Segments, pred func(s Segment) bool) Segments {
+// CoverageUpTo walks RemoveOverlaps(ss) in order, accumulating covered length
+// until limit is reached. It returns the covered length, which is limit if it
+// was reached, and the point at which the walk stopped.
+// If limit is not reached, it returns the total covered length and the end
+// of the last segment (or 0 for empty input).
+// A limit <= 0 is treated as 0, so the walk stops at the first start.
+// It is the inverse of SumDeltasUpToPoint.
+func (ss Segments) CoverageUpTo(limit int64) (covered int64, cutoff int64) {
+	limit = max(limit, 0)
+	for _, s := range RemoveOverlaps(ss) {
+		if covered+s.Delta() >= limit {
+			return limit, s.start + (limit - covered)
+		}
+		covered += s.Delta()
+		cutoff = s.end
+	}
+	return covered, cutoff
+}
+
+// TakeLength returns the first n units of coverage of ss: it walks
+// RemoveOverlaps(ss) in order, as CoverageUpTo does, and truncates the segment
+// crossing the n boundary. For example, {0, 5}, {10, 20} with n = 8 gives
+// {0, 5}, {10, 13}. If ss covers less than n, all of RemoveOverlaps(ss) is returned.
+// If n <= 0, nil is returned. The input is not modified.
+func (ss Segments) TakeLength(n int64) Segments {
+	if n <= 0 {
+		return nil
+	}
 	var output Segments
-	for _, s := range ss {
-		if pred(s) {
-			output = append(output, s)
+	var covered int64
+	for _, s := range RemoveOverlaps(ss) {
+		if covered+s.Delta() >= n {
+			return append(output, Segment{s.start, s.start + (n - covered)})
 		}
+		covered += s.Delta()
+		output = append(output, s)
 	}
 	return output
 }
 
-//////// SET OPERATIONS ////////
-
-// RemoveOverlaps takes out overlapping areas in a slice of segments.
-// Note segments where start > end are discarded.
-// This function also sorts segments by Start, so the output will be ordered.
-func RemoveOverlaps(ss Segments) Segments {
-	// Sort the segments by their starts.
-	// In order to not sort this in-place, we make a copy of ss.
-	ssSorted := append(Segments{}, ss...)
-	sort.Slice(ssSorted, func(i, j int) bool { return ssSorted[i].start < ssSorted[j].start })
-	rightMost := int64(math.MinInt64)
-	var output Segments
+// CoverageInWindow returns the length of window covered by ss, not counting
+// overlapping regions twice. The segments are clipped to the window as they are
+// summed, so no clipped slice is built; the only allocation is the copy of ss
+// made by RemoveOverlaps.
+func (ss Segments) CoverageInWindow(window Segment) int64 {
+	return coverageInWindow(RemoveOverlaps(ss), window)
+}
 
-	for _, s := range ssSorted {
-		// Do we need to start a new segment?
-		if rightMost < s.start {
-			output = append(output, Segment{s.start, s.end})
-			rightMost = s.end
-			// Ensuring the length of the output is positive means that segments
-			// starting at math.MinInt64 are skipped.
-		} else if n := len(output); n > 0 && rightMost < s.end {
-			// Do we need to update the end of the existing last segment?
-			output[n-1].end = s.end
-			rightMost = s.end
+// coverageInWindow is CoverageInWindow for normalized input: it does not
+// allocate, so callers querying many windows can normalize only once.
+func coverageInWindow(normalized Segments, window Segment) int64 {
+	var output int64
+	for _, s := range normalized {
+		if s.start >= window.end {
+			break
+		}
+		if lo, hi := max(s.start, window.start), min(s.end, window.end); lo < hi {
+			output += hi - lo
 		}
 	}
 	return output
 }
 
-// Union finds the overlap between slices of segments.
-func Union(ss ...Segments) Segments {
-	var tt Segments
-	for _, s := range ss {
-		tt = append(tt, s...)
+// CoverageFraction returns the fraction of superset covered by ss, in [0, 1]:
+// the segments are clipped to the superset, as per CoverageInWindow, and the
+// covered length is divided by the length of the superset.
+// If the superset has zero length, 0 is returned.
+func CoverageFraction(superset Segment, ss Segments) float64 {
+	if !superset.IsDeltaPositive() {
+		return 0
 	}
-	return RemoveOverlaps(tt)
+	return float64(ss.CoverageInWindow(superset)) / float64(superset.Delta())
 }
 
-// SimpleIntersection returns the intersection between segment s and segment t
-// (and a bool indicating whether there is an intersection).
-func SimpleIntersection(s, t Segment) (Segment, bool) {
-	switch {
-	case s.start <= t.start && s.end <= t.end && s.end >= t.start:
-		return Segment{t.start, s.end}, true
-	case s.start >= t.start && s.end >= t.end && s.start <= t.end:
-		return Segment{s.start, t.end}, true
-	case s.IsSubSegment(t):
-		return Segment{s.start, s.end}, true
-	case t.IsSubSegment(s):
-		return Segment{t.start, t.end}, true
+// RecencyWeightedCoverage returns the covered length of ss, where each length
+// is weighted by an exponential decay with half-life halfLife, based on how long
+// before now the covering segment ends. Segments ending at or after now have weight 1.
+// Overlapping regions are counted once, with the weight of the most recent
+// segment covering them, which is the largest weight.
+// If halfLife <= 0, the decay is not well-defined, so 0 is returned.
+func (ss Segments) RecencyWeightedCoverage(now int64, halfLife int64) float64 {
+	if halfLife <= 0 {
+		return 0
 	}
-	return Segment{}, false
-}
+	byEnd := append(Segments{}, ss...)
+	sort.Slice(byEnd, func(i, j int) bool { return byEnd[i].end > byEnd[j].end })
 
-// Intersect returns the segments where two slices of segments overlap.
-func Intersect(ss, tt Segments) Segments {
-	var output Segments
-	newS, newT := RemoveOverlaps(ss), RemoveOverlaps(tt)
-	sLen, tLen := len(newS), len(newT)
-	for i, j := 0, 0; i < sLen && j < tLen; {
-		if intersect, ok := SimpleIntersection(newS[i], newT[j]); ok {
-			output = append(output, intersect)
-		}
+	var output float64
+	var covered Segments
+	for _, s := range byEnd {
+		age := max(now-s.end, 0)
+		weight := math.Exp2(-float64(age) / float64(halfLife))
+		output += weight * float64(SetDiff(Segments{s}, covered).SumDeltas())
+		covered = Union(covered, Segments{s})
+	}
+	return output
+}
 
-		if delta := newS[i].End() - newT[j].End(); delta == 0 {
-			// If the two segments have the same end time, no remaining segments can
-			// intersect with either segment, so advance both iterators.
-			i++
-			j++
-		} else if delta > 0 {
-			// If the segment from newS ends after the segment from newT, no other
-			// remaining segment from newS can intersect with the newT segment, so
-			// advance newT to the next segment.
-			j++
-		} else {
-			// The segment from newT must end after the segment from newS, so see
-			// the above comment.
-			i++
+// Signature returns a lossy fingerprint of the coverage of ss over window.
+// The window is split into bits sub-windows of (nearly) equal length, and bit i
+// of the signature is set if more than half of sub-window i is covered.
+// Sets with similar coverage get signatures at a small Hamming distance, so
+// signatures can cheaply pre-filter candidates before an exact comparison.
+// If bits is not in [1, 64] or window has zero length, 0 is returned.
+func (ss Segments) Signature(window Segment, bits int) uint64 {
+	if bits < 1 || bits > 64 || !window.IsDeltaPositive() {
+		return 0
+	}
+	normalized := RemoveOverlaps(ss)
+	n := int64(bits)
+	// boundary returns the start of sub-window i, without overflowing.
+	boundary := func(i int64) int64 {
+		return window.start + window.Delta()/n*i + window.Delta()%n*i/n
+	}
+	var output uint64
+	for i := int64(0); i < n; i++ {
+		sub := Segment{boundary(i), boundary(i + 1)}
+		if 2*coverageInWindow(normalized, sub) > sub.Delta() {
+			output |= 1 << uint(i)
 		}
 	}
 	return output
 }
 
-// GetOverlaps returns segments from the intersection between any pair of segments.
-// Note: the output segments do not overlap by design.
-func GetOverlaps(ss Segments) Segments {
-	var output Segments
-	for i, s := range ss {
-		for _, t := range ss[:i] {
-			if intersect, ok := SimpleIntersection(s, t); ok {
-				output = append(output, intersect)
+// CoverageSample is the covered length of a window ending at a point.
+type CoverageSample struct {
+	At       int64
+	Coverage int64
+}
+
+// SlidingCoverage returns, for each point At = over.start, over.start+step, ...
+// up to over.end, the covered length of ss in the trailing window
+// [At-windowLen, At], not counting overlapping regions twice.
+// The windows are computed with an incremental sweep over RemoveOverlaps(ss),
+// rather than from scratch.
+// If step <= 0 or windowLen < 0, nil is returned.
+func (ss Segments) SlidingCoverage(windowLen, step int64, over Segment) []CoverageSample {
+	if step <= 0 || windowLen < 0 {
+		return nil
+	}
+	normalized := RemoveOverlaps(ss)
+	// cumulative[k] is the total length of normalized[:k].
+	cumulative := make([]int64, len(normalized)+1)
+	for k, s := range normalized {
+		cumulative[k+1] = cumulative[k] + s.Delta()
+	}
+	// upTo returns a function giving the covered length below a point, which
+	// must be called with non-decreasing points.
+	upTo := func() func(p int64) int64 {
+		k := 0
+		return func(p int64) int64 {
+			for k < len(normalized) && normalized[k].end <= p {
+				k++
 			}
+			if k < len(normalized) && normalized[k].start < p {
+				return cumulative[k] + p - normalized[k].start
+			}
+			return cumulative[k]
 		}
 	}
-	return RemoveOverlaps(output)
+	windowEnd, windowStart := upTo(), upTo()
+
+	var output []CoverageSample
+	for at := over.start; at <= over.end; at += step {
+		output = append(output, CoverageSample{at, windowEnd(at) - windowStart(at-windowLen)})
+		if over.end-at < step {
+			break
+		}
+	}
+	return output
 }
 
-// Complement takes as input a slice of segments, and a superset segment.
-// It returns all segments in the superset that are not in the slice.
-// More precisely, Complement(superset, ss) == tt if tt is the slice of segments
-// with smallest length such that Union(ss, tt) == superset.
-func Complement(superset Segment, ss Segments) Segments {
-	// If the superset is not well-defined, return the empty slice.
-	if !superset.IsDeltaPositive() {
+// BinCounts splits window into consecutive bins of width binWidth (the last bin
+// may be shorter), and returns for each bin how many segments of ss are active in it.
+// A segment is active in a bin if it covers a positive length of the bin, so a
+// segment spanning several bins counts in each of them, but a segment ending
+// where a bin starts does not count in it. A point segment counts in the bin
+// [binStart, binEnd) holding it.
+// If binWidth <= 0 or window has zero length, nil is returned.
+func (ss Segments) BinCounts(window Segment, binWidth int64) []int {
+	if binWidth <= 0 || !window.IsDeltaPositive() {
 		return nil
 	}
-	output := Segments{Segment{superset.start, superset.end}}
-
-	for _, s := range RemoveOverlaps(ss) {
-		// If the superset is a subset of s, return the nil segment.
-		if superset.IsSubSegment(s) {
-			return nil
-		}
-		// If there is no intersection between the superset and s, continue.
-		if _, ok := SimpleIntersection(superset, s); !ok {
+	output := make([]int, (window.Delta()+binWidth-1)/binWidth)
+	for _, s := range ss {
+		if s.start == s.end {
+			if window.start <= s.start && s.start < window.end {
+				output[(s.start-window.start)/binWidth]++
+			}
 			continue
 		}
-		if s.start <= superset.start {
-			output[len(output)-1].start = s.end
+		lo, hi := max(s.start, window.start), min(s.end, window.end)
+		if lo >= hi {
 			continue
 		}
-		if s.end >= superset.end {
-			output[len(output)-1].end = s.start
-			continue
+		for i := (lo - window.start) / binWidth; i <= (hi-window.start-1)/binWidth; i++ {
+			output[i]++
 		}
-		// Now, we know s must be a strict subsegment of superset.
-		// We also are guaranteed that the segment to append has positive delta.
-		output[len(output)-1].end = s.start
-		output = append(output, Segment{s.end, superset.end})
 	}
 	return output
 }
 
-// SetDiff returns the difference in segments between two sets of segments.
-// It is a generalization of the function Complement().
-// If SetDiff(a, b Segments) == c Segments, then c is the slice of smallest length
-// such that Union(c, b) == a.
-func SetDiff(ss, tt Segments) Segments {
+// SegmentsWithPredicate returns a subset of segments that meet a predicate function.
+func SegmentsWithPredicate(ss Segments, pred func(s Segment) bool) Segments {
 	var output Segments
 	for _, s := range ss {
-		output = append(output, Complement(s, tt)...)
+		if pred(s) {
+			output = append(output, s)
+		}
 	}
-	return RemoveOverlaps(output)
+	return output
 }
 
-// IsIntersectionEmpty returns whether the intersection between
-// a segment and a slice of segments is empty.
-func (s Segment) IsIntersectionEmpty(tt Segments) bool {
-	return len(Intersect(Segments{s}, tt)) == 0
+// MapSegments applies fn to every segment of ss, and returns the results for
+// which fn returns true, in input order. Unlike SegmentsWithPredicate, fn can
+// transform the segments, for example clipping them; the output is not
+// normalized, so it may overlap.
+func MapSegments(ss Segments, fn func(Segment) (Segment, bool)) Segments {
+	var output Segments
+	for _, s := range ss {
+		if mapped, ok := fn(s); ok {
+			output = append(output, mapped)
+		}
+	}
+	return output
 }
 
-// IsSubSegment reports whether segment s is a sub-segment of segment t.
-func (s Segment) IsSubSegment(t Segment) bool {
-	return s.start >= t.start && s.end <= t.end
+// IsNormalized reports whether ss is in the form returned by RemoveOverlaps:
+// every segment has start <= end, and the segments are sorted by start, with
+// neither overlaps nor touching endpoints between them. Fast paths assuming
+// normalized input can use it to check their precondition in O(n), without allocating.
+func (ss Segments) IsNormalized() bool {
+	return ss.IsSortedDisjoint(false)
 }
 
-// IsPointInSegments returns true if and only if the point is contained
-// in any of the segments in a slice of segments.
-func IsPointInSegments(p int64, ss Segments) bool {
-	for _, s := range ss {
-		if IsPointInSegment(p, s) {
-			return true
+// IsSortedDisjoint reports whether every segment of ss has start <= end, and
+// the segments are sorted by start without overlapping. If allowTouching is
+// true, consecutive segments may share an endpoint.
+func (ss Segments) IsSortedDisjoint(allowTouching bool) bool {
+	for i, s := range ss {
+		if s.end < s.start {
+			return false
+		}
+		if i == 0 {
+			continue
+		}
+		if prev := ss[i-1]; prev.end > s.start || (prev.end == s.start && !allowTouching) {
+			return false
 		}
 	}
-	return false
+	return true
 }
 
-// IsPointInSegment returns true if and only if the point is contained in the segment.
-func IsPointInSegment(p int64, s Segment) bool {
-	return s.start <= p && p <= s.end
+// PruneShort returns the segments of ss with length at least minLen, in input order.
+func PruneShort(ss Segments, minLen int64) Segments {
+	return SegmentsWithPredicate(ss, func(s Segment) bool { return s.Delta() >= minLen })
+}
+
+// Clone returns a copy of ss in freshly allocated storage, so that modifying
+// the copy in place, for example with LinearTransform, does not modify ss.
+// Segment is a value type, so copying the slice copies every segment.
+// Clone returns nil if ss is nil.
+func (ss Segments) Clone() Segments {
+	if ss == nil {
+		return nil
+	}
+	return append(Segments{}, ss...)
+}
+
+// Sort sorts ss in place by start, then by end. Unlike RemoveOverlaps, it does not merge segments.
+func (ss Segments) Sort() {
+	sort.Slice(ss, func(i, j int) bool {
+		return ss[i].start < ss[j].start || (ss[i].start == ss[j].start && ss[i].end < ss[j].end)
+	})
+}
+
+// Sorted returns a copy of ss sorted by start, then by end. ss is not modified.
+func (ss Segments) Sorted() Segments {
+	output := ss.Clone()
+	output.Sort()
+	return output
+}
+
+// InsertionIndex returns the index at which s should be inserted into ss to
+// keep ss sorted by start, then by end. If ss already holds segments equal to s,
+// the returned index is after them.
+// ss must already be sorted by start, then by end; this is not checked.
+func (ss Segments) InsertionIndex(s Segment) int {
+	return sort.Search(len(ss), func(i int) bool {
+		return ss[i].start > s.start || (ss[i].start == s.start && ss[i].end > s.end)
+	})
+}
+
+//////// SPLIT SEGMENTS ////////
+
+// Split cuts segment s at point p, returning the left piece [start, p] and the
+// right piece [p, end], and whether p lies within s. If p is not in s, s is
+// not split, and two zero Segments are returned.
+// If p is an endpoint of s, one of the pieces is the zero-length point segment
+// at p: Split(start) returns {start, start} and s, and Split(end) returns s and {end, end}.
+func (s Segment) Split(p int64) (Segment, Segment, bool) {
+	if !IsPointInSegment(p, s) {
+		return Segment{}, Segment{}, false
+	}
+	return Segment{s.start, p}, Segment{p, s.end}, true
+}
+
+// Split cuts every segment of ss strictly containing point p into its left and
+// right pieces, keeping the input order. Segments for which p is an endpoint
+// are left as is, so that no zero-length pieces are introduced.
+func (ss Segments) Split(p int64) Segments {
+	var output Segments
+	for _, s := range ss {
+		if s.start < p && p < s.end {
+			left, right, _ := s.Split(p)
+			output = append(output, left, right)
+			continue
+		}
+		output = append(output, s)
+	}
+	return output
+}
+
+// ToGridCells splits a segment along a grid of cells of size cellSize, with
+// cell boundaries at multiples of cellSize. It returns one segment per cell
+// that s intersects, clipped to the cell boundaries, in increasing order.
+// For example, Segment{3, 12}.ToGridCells(5) is {3, 5}, {5, 10}, {10, 12}.
+// If cellSize <= 0, the grid is not well-defined, so nil is returned.
+func (s Segment) ToGridCells(cellSize int64) Segments {
+	if cellSize <= 0 {
+		return nil
+	}
+	var output Segments
+	p, step := s.start, cellSize-positiveMod(s.start, cellSize)
+	// As in Chunk, comparing the remaining length avoids overflowing p + step
+	// near math.MaxInt64; it is compared as a uint64 so that segments longer than
+	// math.MaxInt64 are handled too.
+	for uint64(s.end-p) > uint64(step) {
+		output = append(output, Segment{p, p + step})
+		p += step
+		step = cellSize
+	}
+	return append(output, Segment{p, s.end})
+}
+
+// Chunk splits a segment into consecutive pieces of length size, starting from
+// s.start, with a shorter last piece if needed, so that the union of the pieces
+// is s. For example, Segment{0, 10}.Chunk(4) is {0, 4}, {4, 8}, {8, 10}.
+// Unlike ToGridCells, the pieces are aligned on s.start rather than on multiples of size.
+// A point segment gives itself. If size <= 0, nil is returned.
+func (s Segment) Chunk(size int64) Segments {
+	if size <= 0 {
+		return nil
+	}
+	var output Segments
+	p := s.start
+	// Comparing the remaining length avoids overflowing p + size near math.MaxInt64.
+	for s.end-p > size {
+		output = append(output, Segment{p, p + size})
+		p += size
+	}
+	return append(output, Segment{p, s.end})
+}
+
+// Chunk splits every segment of ss into pieces of length at most size, as per
+// Segment.Chunk, keeping the input order. If size <= 0, nil is returned.
+func (ss Segments) Chunk(size int64) Segments {
+	var output Segments
+	for _, s := range ss {
+		output = append(output, s.Chunk(size)...)
+	}
+	return output
+}
+
+// SnapToGrid rounds the start of s down and its end up to multiples of grid,
+// so that the snapped segment always contains s. Negative coordinates round
+// in the same direction, so Segment{-7, -3}.SnapToGrid(5) is {-10, 0}.
+// An end whose multiple of grid lies beyond the int64 range is clamped to
+// math.MinInt64 or math.MaxInt64, so that the output still contains s.
+// If grid <= 0, the grid is not well-defined, so s is returned unchanged.
+func (s Segment) SnapToGrid(grid int64) Segment {
+	if grid <= 0 {
+		return s
+	}
+	return Segment{floorToMultiple(s.start, grid), ceilToMultiple(s.end, grid)}
+}
+
+// SnapToGrid snaps every segment of ss to multiples of grid, as per
+// Segment.SnapToGrid, and returns the result with overlaps removed, as per
+// RemoveOverlaps. The input is not modified.
+func (ss Segments) SnapToGrid(grid int64) Segments {
+	var snapped Segments
+	for _, s := range ss {
+		snapped = append(snapped, s.SnapToGrid(grid))
+	}
+	return RemoveOverlaps(snapped)
+}
+
+// positiveMod returns p modulo m, in [0, m). m must be positive.
+func positiveMod(p, m int64) int64 {
+	mod := p % m
+	if mod < 0 {
+		mod += m
+	}
+	return mod
+}
+
+// floorToMultiple returns the largest multiple of m that is <= p, or
+// math.MinInt64 if that multiple is below the int64 range. m must be positive.
+func floorToMultiple(p, m int64) int64 {
+	mod := positiveMod(p, m)
+	if p < math.MinInt64+mod {
+		return math.MinInt64
+	}
+	return p - mod
+}
+
+// ceilToMultiple returns the smallest multiple of m that is >= p, or
+// math.MaxInt64 if that multiple is above the int64 range. m must be positive.
+func ceilToMultiple(p, m int64) int64 {
+	mod := positiveMod(p, m)
+	if mod == 0 {
+		return p
+	}
+	if p > math.MaxInt64-(m-mod) {
+		return math.MaxInt64
+	}
+	return p + (m - mod)
+}
+
+//////// SET OPERATIONS ////////
+
+// RemoveOverlaps takes out overlapping areas in a slice of segments.
+// Note segments where start > end are discarded.
+// This function also sorts segments by Start, so the output will be ordered.
+func RemoveOverlaps(ss Segments) Segments {
+	return removeOverlaps[int64](ss)
+}
+
+// MergeSorted takes out overlapping areas in a slice of segments already
+// sorted by start, like RemoveOverlaps, but in a single O(n) pass without
+// copying and sorting the input. This suits streams of pre-sorted segments.
+// ss must be sorted by start; this is not checked, and if it does not hold,
+// the output is unspecified and may overlap. The input is not modified.
+func MergeSorted(ss Segments) Segments {
+	return mergeSorted[int64](nil, ss)
+}
+
+// Normalize sorts and merges ss in place, so that it holds the same segments as
+// RemoveOverlaps(ss) would return, reusing its backing array instead of allocating.
+// The slice is truncated to the merged segments. This invalidates prior aliases
+// of the slice: they see the sorted, partly overwritten backing array.
+func (ss *Segments) Normalize() {
+	sortByStart[int64](*ss)
+	*ss = mergeSorted[int64]((*ss)[:0], *ss)
+}
+
+// CountedSegment is a segment annotated with how many segments of a set were merged into it.
+type CountedSegment struct {
+	Seg         Segment
+	SourceCount int
+}
+
+// RemoveOverlapsCounted returns the same segments as RemoveOverlaps, each with
+// the number of input segments merged into it.
+func RemoveOverlapsCounted(ss Segments) []CountedSegment {
+	ssSorted := append(Segments{}, ss...)
+	sort.Slice(ssSorted, func(i, j int) bool { return ssSorted[i].start < ssSorted[j].start })
+	var output []CountedSegment
+
+	for _, s := range ssSorted {
+		n := len(output)
+		if n == 0 || output[n-1].Seg.end < s.start {
+			output = append(output, CountedSegment{Segment{s.start, s.end}, 1})
+			continue
+		}
+		output[n-1].SourceCount++
+		output[n-1].Seg.end = max(output[n-1].Seg.end, s.end)
+	}
+	return output
+}
+
+// RemoveOverlapsWithGroups returns the same segments as RemoveOverlaps, and
+// for each of them, the increasing indices in ss of the input segments merged
+// into it, so that merged regions can be traced back to their sources.
+func RemoveOverlapsWithGroups(ss Segments) (Segments, [][]int) {
+	order := make([]int, len(ss))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return ss[order[i]].start < ss[order[j]].start })
+	var output Segments
+	var groups [][]int
+
+	for _, i := range order {
+		s := ss[i]
+		n := len(output)
+		if n == 0 || output[n-1].end < s.start {
+			output = append(output, Segment{s.start, s.end})
+			groups = append(groups, []int{i})
+			continue
+		}
+		groups[n-1] = append(groups[n-1], i)
+		output[n-1].end = max(output[n-1].end, s.end)
+	}
+	for _, g := range groups {
+		sort.Ints(g)
+	}
+	return output, groups
+}
+
+// Union finds the overlap between slices of segments.
+func Union(ss ...Segments) Segments {
+	return union[int64](ss...)
+}
+
+// ConcatStrict concatenates slices of segments which should not overlap, such
+// as the shards of a partitioned input, and returns them sorted by start.
+// If two segments from different slices intersect (including touching, as
+// with SimpleIntersection), an error naming them is returned instead.
+// Overlaps between segments of the same slice are allowed.
+func ConcatStrict(slices ...Segments) (Segments, error) {
+	var output Segments
+	var source []int
+	for i, ss := range slices {
+		output = append(output, ss...)
+		for range ss {
+			source = append(source, i)
+		}
+	}
+
+	var err error
+	forEachIntersectingPair(output, func(i, j int) {
+		if err == nil && source[i] != source[j] {
+			err = fmt.Errorf("segment %s from slice %d overlaps segment %s from slice %d: segments not concatenated",
+				output[i], source[i], output[j], source[j])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	output.Sort()
+	return output, nil
+}
+
+// MergeCapped merges overlapping or touching segments like RemoveOverlaps, but
+// never grows a merged segment beyond maxLen. When merging the next segment
+// would exceed the cap, a new output segment is started at the end of the
+// current one instead. The output is sorted by start and does not overlap,
+// though consecutive segments touch wherever the cap was hit. An input segment
+// that is already longer than maxLen is not split.
+// Segments with the same start are taken longest first, so the output does not
+// depend on the order of ss. A negative maxLen is treated as 0, in which case
+// overlapping segments are cut where they overlap instead of being merged.
+func MergeCapped(ss Segments, maxLen int64) Segments {
+	maxLen = max(maxLen, 0)
+	ssSorted := append(Segments{}, ss...)
+	sort.Slice(ssSorted, func(i, j int) bool {
+		if ssSorted[i].start != ssSorted[j].start {
+			return ssSorted[i].start < ssSorted[j].start
+		}
+		return ssSorted[i].end > ssSorted[j].end
+	})
+	var output Segments
+
+	for _, s := range ssSorted {
+		n := len(output)
+		if n == 0 || output[n-1].end < s.start {
+			output = append(output, s)
+			continue
+		}
+		last := &output[n-1]
+		if s.end <= last.end {
+			// s is already covered by the current segment.
+			continue
+		}
+		if s.end-last.start <= maxLen {
+			last.end = s.end
+			continue
+		}
+		// Merging would exceed the cap, so carry on from where the current segment ends.
+		output = append(output, Segment{last.end, s.end})
+	}
+	return output
+}
+
+// MergeWithTolerance behaves like RemoveOverlaps, but also merges segments
+// separated by a gap of at most gap. For example, with gap = 3, {0, 10} and
+// {12, 20} merge into {0, 20}, but {0, 10} and {20, 30} stay separate.
+// With gap = 0, it is equivalent to RemoveOverlaps, so touching segments are
+// still merged. A negative gap is treated as 0.
+func MergeWithTolerance(ss Segments, gap int64) Segments {
+	gap = max(gap, 0)
+	var output Segments
+	for _, s := range ss.Sorted() {
+		if n := len(output); n > 0 && s.start-output[n-1].end <= gap {
+			output[n-1].end = max(output[n-1].end, s.end)
+			continue
+		}
+		output = append(output, s)
+	}
+	return output
+}
+
+// SimpleIntersection returns the intersection between segment s and segment t
+// (and a bool indicating whether there is an intersection).
+// It is symmetric in s and t, and segments touching at a point intersect in
+// that point segment.
+func SimpleIntersection(s, t Segment) (Segment, bool) {
+	return simpleIntersection[int64](s, t)
+}
+
+// Overlaps reports whether segments s and t intersect, like the bool returned
+// by SimpleIntersection, without building the intersection.
+// As with SimpleIntersection, segments touching at a point overlap.
+func (s Segment) Overlaps(t Segment) bool {
+	return s.start <= t.end && t.start <= s.end
+}
+
+// OverlapLength returns the length of the intersection of segments s and t,
+// or 0 if they do not intersect, without building the intersection.
+// Segments touching at a point overlap with length 0.
+func (s Segment) OverlapLength(t Segment) int64 {
+	return max(0, min(s.end, t.end)-max(s.start, t.start))
+}
+
+// IsAdjacent reports whether segments s and t abut without overlapping: one ends
+// where the other starts, so that they intersect only in that point. For example,
+// {0, 5} and {5, 10} are adjacent, but {0, 5} and {4, 10}, or {0, 5} and {6, 10}, are not.
+// A point segment at an endpoint of the other segment is adjacent to it.
+func (s Segment) IsAdjacent(t Segment) bool {
+	lo, hi := max(s.start, t.start), min(s.end, t.end)
+	return lo == hi && (s.end == t.start || t.end == s.start)
+}
+
+// Clamp restricts segment s to bounds, returning the part of s within bounds,
+// and whether any part of s lies within bounds. A segment touching bounds at a
+// single endpoint is clamped to that point segment.
+func (s Segment) Clamp(bounds Segment) (Segment, bool) {
+	return SimpleIntersection(s, bounds)
+}
+
+// Clamp restricts every segment of ss to bounds, dropping segments that lie
+// entirely outside bounds. The output keeps the input order.
+func (ss Segments) Clamp(bounds Segment) Segments {
+	var output Segments
+	for _, s := range ss {
+		if clamped, ok := s.Clamp(bounds); ok {
+			output = append(output, clamped)
+		}
+	}
+	return output
+}
+
+// Stitch joins two segments for timeline stitching.
+// If a and b overlap or touch, result is their union, gap is the zero Segment,
+// and overlapping is true. This includes the case where one segment is nested
+// in the other, in which case result is the outer segment.
+// Otherwise, result spans both segments, gap is the segment between them,
+// and overlapping is false. The order of a and b does not matter.
+func Stitch(a, b Segment) (result Segment, gap Segment, overlapping bool) {
+	if b.start < a.start {
+		a, b = b, a
+	}
+	result = Segment{a.start, b.end}
+	if a.end > b.end {
+		result.end = a.end
+	}
+	if b.start <= a.end {
+		return result, Segment{}, true
+	}
+	return result, Segment{a.end, b.start}, false
+}
+
+// Compare3Way performs one step of a two-pointer sweep over two sorted,
+// non-overlapping slices of segments, where s is the current segment of the
+// first slice and t the current segment of the second.
+// It returns the intersection of s and t (and whether there is one), and which
+// iterator to advance next: -1 for the first slice, +1 for the second, and 0 for both.
+func (s Segment) Compare3Way(t Segment) (overlap Segment, hasOverlap bool, advance int) {
+	return compare3Way[int64](s, t)
+}
+
+// Intersect returns the segments where all the slices of segments overlap.
+// Intersect(x, y, z) is equivalent to Intersect(Intersect(x, y), z).
+// With a single slice, the result is the same as RemoveOverlaps, and with no
+// slices, it is nil.
+func Intersect(ss ...Segments) Segments {
+	return intersect[int64](ss...)
+}
+
+// GetOverlaps returns segments from the intersection between any pair of segments.
+// Note: the output segments do not overlap by design.
+func GetOverlaps(ss Segments) Segments {
+	var output Segments
+	for i, s := range ss {
+		for _, t := range ss[:i] {
+			if intersect, ok := SimpleIntersection(s, t); ok {
+				output = append(output, intersect)
+			}
+		}
+	}
+	return RemoveOverlaps(output)
+}
+
+// OverlapLengthHistogram returns, for each overlap length, how many pairs of
+// segments in ss intersect with that length. As with SimpleIntersection,
+// segments that only touch intersect with length 0.
+// Pairs of segments that do not intersect do not contribute.
+func (ss Segments) OverlapLengthHistogram() map[int64]int {
+	output := make(map[int64]int)
+	forEachIntersectingPair(ss, func(i, j int) {
+		end := ss[i].end
+		if ss[j].end < end {
+			end = ss[j].end
+		}
+		start := ss[i].start
+		if ss[j].start > start {
+			start = ss[j].start
+		}
+		output[end-start]++
+	})
+	return output
+}
+
+// OverlapEdges returns the pairs of indices {i, j}, with i < j, of the segments
+// of ss that intersect, sorted by i and then j. Segments that only touch intersect,
+// as with SimpleIntersection. This is the edge list of the sparse overlap graph of ss.
+func (ss Segments) OverlapEdges() [][2]int {
+	var output [][2]int
+	forEachIntersectingPair(ss, func(i, j int) {
+		if i > j {
+			i, j = j, i
+		}
+		output = append(output, [2]int{i, j})
+	})
+	sort.Slice(output, func(a, b int) bool {
+		return output[a][0] < output[b][0] || (output[a][0] == output[b][0] && output[a][1] < output[b][1])
+	})
+	return output
+}
+
+// ClustersWithGap groups the segments of ss into clusters, where two segments
+// are in the same cluster if they are within maxGap of each other, directly or
+// through other segments of the cluster. With maxGap = 0, clusters are the
+// groups of segments which overlap or touch.
+// Clusters are sorted by start, as are the segments within each cluster.
+func (ss Segments) ClustersWithGap(maxGap int64) []Segments {
+	ssSorted := append(Segments{}, ss...)
+	sort.SliceStable(ssSorted, func(i, j int) bool { return ssSorted[i].start < ssSorted[j].start })
+	var output []Segments
+	var rightMost int64
+
+	for _, s := range ssSorted {
+		if n := len(output); n > 0 && s.start-rightMost <= maxGap {
+			output[n-1] = append(output[n-1], s)
+			rightMost = max(rightMost, s.end)
+			continue
+		}
+		output = append(output, Segments{s})
+		rightMost = s.end
+	}
+	return output
+}
+
+// forEachIntersectingPair calls fn(i, j) once for every pair of indices of ss
+// whose segments intersect, using a sweep over the segments sorted by start.
+// The order of the calls is unspecified, and i and j are in no particular order.
+func forEachIntersectingPair(ss Segments, fn func(i, j int)) {
+	order := make([]int, len(ss))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return ss[order[a]].start < ss[order[b]].start })
+
+	var active []int
+	for _, i := range order {
+		// Drop the active segments which end before this one starts:
+		// since starts are increasing, they cannot intersect any later segment.
+		kept := active[:0]
+		for _, j := range active {
+			if ss[j].end >= ss[i].start {
+				kept = append(kept, j)
+				fn(j, i)
+			}
+		}
+		active = append(kept, i)
+	}
+}
+
+// DedupByOverlap removes redundant segments by non-max suppression.
+// Segments are considered from longest to shortest (keeping input order between
+// segments of equal length), and a segment is kept unless its intersection over
+// union with an already kept segment is greater than minIoU.
+// The kept segments are returned from longest to shortest.
+func DedupByOverlap(ss Segments, minIoU float64) Segments {
+	byLength := append(Segments{}, ss...)
+	sort.SliceStable(byLength, func(i, j int) bool { return byLength[i].Delta() > byLength[j].Delta() })
+	var output Segments
+
+	for _, s := range byLength {
+		suppressed := false
+		for _, k := range output {
+			if iou(s, k) > minIoU {
+				suppressed = true
+				break
+			}
+		}
+		if !suppressed {
+			output = append(output, s)
+		}
+	}
+	return output
+}
+
+// SequenceOverlapScore compares two ordered sequences of segments, and returns
+// the total intersection over union of the best order-preserving alignment
+// between them, where each segment is aligned with at most one segment of the
+// other sequence. Identical sequences of n segments of positive length score n.
+// The alignment is found by dynamic programming, in the same way as a longest
+// common subsequence, taking O(len(x) * len(y)) time and space.
+func SequenceOverlapScore(x, y Segments) float64 {
+	// score[i][j] is the best score aligning x[:i] with y[:j].
+	score := make([][]float64, len(x)+1)
+	for i := range score {
+		score[i] = make([]float64, len(y)+1)
+	}
+	for i := 1; i <= len(x); i++ {
+		for j := 1; j <= len(y); j++ {
+			score[i][j] = max(score[i-1][j], score[i][j-1], score[i-1][j-1]+iou(x[i-1], y[j-1]))
+		}
+	}
+	return score[len(x)][len(y)]
+}
+
+// iou returns the length of the intersection of s and t divided by the length
+// of their union, or 0 if they do not intersect.
+// Two intersecting segments whose union has zero length have an iou of 1.
+func iou(s, t Segment) float64 {
+	intersect, ok := SimpleIntersection(s, t)
+	if !ok {
+		return 0
+	}
+	union := s.Delta() + t.Delta() - intersect.Delta()
+	if union == 0 {
+		return 1
+	}
+	return float64(intersect.Delta()) / float64(union)
+}
+
+// Jaccard returns the similarity of two sets of segments, as the covered length
+// of their intersection divided by the covered length of their union, in [0, 1].
+// Overlaps are removed first, so segments overlapping within x or within y do
+// not skew the ratio. If the union has zero length, 0 is returned.
+func Jaccard(x, y Segments) float64 {
+	union := Union(x, y).SumDeltas()
+	if union == 0 {
+		return 0
+	}
+	return float64(Intersect(x, y).SumDeltas()) / float64(union)
+}
+
+// MinimalCover returns a smallest subset of pieces whose union covers target,
+// sorted by start, and whether pieces can cover target at all.
+// It uses the classic greedy algorithm: starting from target.start, it
+// repeatedly picks the piece reaching furthest right among those starting at
+// or before the covered prefix. Pieces touching at an endpoint join up.
+func MinimalCover(target Segment, pieces Segments) (Segments, bool) {
+	sorted := append(Segments{}, pieces...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].start < sorted[j].start })
+	var output Segments
+	reach := target.start
+
+	for i := 0; ; {
+		best := -1
+		for ; i < len(sorted) && sorted[i].start <= reach; i++ {
+			if best == -1 || sorted[i].end > sorted[best].end {
+				best = i
+			}
+		}
+		// The chosen piece must reach target.start, and then make progress.
+		if best == -1 || sorted[best].end < reach || (len(output) > 0 && sorted[best].end == reach) {
+			return nil, false
+		}
+		output = append(output, sorted[best])
+		if reach = sorted[best].end; reach >= target.end {
+			return output, true
+		}
+	}
+}
+
+// PackWithinWindows places jobs of the given durations inside the free windows,
+// and returns the placed segments in job order, and whether every job fits.
+// It uses a first-fit strategy: each job in turn is placed at the earliest free
+// point of the first window of RemoveOverlaps(windows) with enough room left.
+// Consecutive jobs in a window touch, but do not otherwise overlap.
+// Negative durations never fit.
+func PackWithinWindows(jobs []int64, windows Segments) (Segments, bool) {
+	free := RemoveOverlaps(windows)
+	var output Segments
+	for _, d := range jobs {
+		if d < 0 {
+			return nil, false
+		}
+		placed := false
+		for i := range free {
+			if free[i].Delta() >= d {
+				output = append(output, Segment{free[i].start, free[i].start + d})
+				free[i].start += d
+				placed = true
+				break
+			}
+		}
+		if !placed {
+			return nil, false
+		}
+	}
+	return output, true
+}
+
+// Complement takes as input a slice of segments, and a superset segment.
+// It returns all segments in the superset that are not in the slice.
+// More precisely, Complement(superset, ss) == tt if tt is the slice of segments
+// with smallest length such that Union(ss, tt) == superset.
+func Complement(superset Segment, ss Segments) Segments {
+	return complement[int64](superset, ss)
+}
+
+// Subtract returns the parts of s not covered by ss, sorted by start.
+// It is Complement(s, ss), except that a point segment not in ss is returned
+// as is, and the output is an empty, non-nil slice if s is fully covered.
+func (s Segment) Subtract(ss Segments) Segments {
+	if s.start == s.end && !IsPointInSegments(s.start, ss) {
+		return Segments{s}
+	}
+	if output := Complement(s, ss); output != nil {
+		return output
+	}
+	return Segments{}
+}
+
+// Partition splits the superset into the pieces covered by ss and the pieces
+// not covered by it, in a single pass over RemoveOverlaps(ss). Both outputs are
+// sorted by start, and Union(covered, uncovered) == superset. Covered and
+// uncovered pieces only meet at their endpoints, so they never overlap.
+// For a superset of positive length, covered is Intersect(Segments{superset}, ss)
+// and uncovered is Complement(superset, ss). A point superset not in ss is
+// returned as uncovered, so that the union still gives the superset.
+// If superset has end < start, both outputs are nil.
+func Partition(superset Segment, ss Segments) (covered, uncovered Segments) {
+	if superset.end < superset.start {
+		return nil, nil
+	}
+	cursor := superset.start
+	for _, s := range RemoveOverlaps(ss) {
+		c, ok := SimpleIntersection(superset, s)
+		if !ok {
+			continue
+		}
+		if c.start > cursor {
+			uncovered = append(uncovered, Segment{cursor, c.start})
+		}
+		covered = append(covered, c)
+		cursor = c.end
+	}
+	if cursor < superset.end || covered == nil {
+		uncovered = append(uncovered, Segment{cursor, superset.end})
+	}
+	return covered, uncovered
+}
+
+// SetDiff returns the difference in segments between two sets of segments.
+// It is a generalization of the function Complement().
+// If SetDiff(a, b Segments) == c Segments, then c is the slice of smallest length
+// such that Union(c, b) == a.
+func SetDiff(ss, tt Segments) Segments {
+	var output Segments
+	for _, s := range ss {
+		output = append(output, Complement(s, tt)...)
+	}
+	return RemoveOverlaps(output)
+}
+
+// SymmetricDifference returns the regions covered by exactly one of x and y.
+// It is equivalent to Union(SetDiff(x, y), SetDiff(y, x)), but is computed in
+// a single sweep. As with the other set operations, the output is sorted by
+// start and does not overlap; in particular, segments which only touch, such as
+// {0, 2} and {2, 4}, give their union rather than an extra zero-length segment.
+func SymmetricDifference(x, y Segments) Segments {
+	type event struct {
+		at    int64
+		side  int
+		delta int
+	}
+	var events []event
+	for side, ss := range []Segments{x, y} {
+		for _, s := range RemoveOverlaps(ss) {
+			// Zero-length segments do not change which regions are covered.
+			if s.IsDeltaPositive() {
+				events = append(events, event{s.start, side, 1}, event{s.end, side, -1})
+			}
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].at < events[j].at })
+
+	var output Segments
+	var depth [2]int
+	for i := 0; i < len(events); {
+		at := events[i].at
+		for ; i < len(events) && events[i].at == at; i++ {
+			depth[events[i].side] += events[i].delta
+		}
+		if i == len(events) || (depth[0] > 0) == (depth[1] > 0) {
+			continue
+		}
+		piece := Segment{at, events[i].at}
+		if n := len(output); n > 0 && output[n-1].end == piece.start {
+			output[n-1].end = piece.end
+			continue
+		}
+		output = append(output, piece)
+	}
+	return output
+}
+
+// SupersedeLatest builds a "last write wins" timeline from ss.
+// Segments are applied in input order, and each one trims the parts of the
+// earlier segments it covers. The output holds the remaining pieces, sorted by
+// start; pieces may touch, but do not otherwise overlap.
+func SupersedeLatest(ss Segments) Segments {
+	var output Segments
+	for _, s := range ss {
+		var trimmed Segments
+		for _, t := range output {
+			if _, ok := SimpleIntersection(s, t); !ok {
+				trimmed = append(trimmed, t)
+				continue
+			}
+			trimmed = append(trimmed, Complement(t, Segments{s})...)
+		}
+		output = append(trimmed, s)
+	}
+	output.Sort()
+	return output
+}
+
+// DiffSummary returns the total covered lengths added, removed and unchanged
+// when going from the before segments to the after segments.
+// That is, added is the length covered by after but not before, removed is the
+// length covered by before but not after, and unchanged is the length covered by both.
+func DiffSummary(before, after Segments) (added, removed, unchanged int64) {
+	added = SetDiff(after, before).SumDeltas()
+	removed = SetDiff(before, after).SumDeltas()
+	unchanged = Intersect(before, after).SumDeltas()
+	return added, removed, unchanged
+}
+
+// EqualSetsIgnoringSlivers reports whether x and y cover the same regions,
+// ignoring slivers: segments shorter than sliverLen.
+// Slivers are pruned from x and y, and then from their symmetric difference, and
+// the sets are equal if no difference of positive length remains. With sliverLen
+// <= 1, this is exact equality of coverage.
+func EqualSetsIgnoringSlivers(x, y Segments, sliverLen int64) bool {
+	px, py := PruneShort(x, sliverLen), PruneShort(y, sliverLen)
+	diff := Union(SetDiff(px, py), SetDiff(py, px))
+	for _, s := range PruneShort(diff, sliverLen) {
+		if s.IsDeltaPositive() {
+			return false
+		}
+	}
+	return true
+}
+
+// ThreeWayMerge merges two edited versions a and b of the base segments, in
+// the way git merges edits to a file.
+// The changes of each side are its hunks: the segments of SetDiff(side, base),
+// which it added, and those of SetDiff(base, side), which it removed.
+// Regions changed by only one side take that side's coverage, and regions
+// changed in the same way by both sides are merged cleanly. Two hunks from
+// different sides conflict if they overlap with a positive length, as per
+// OverlapLength, without being the same change; hunks which only touch are
+// independent. Added hunks lie outside base and removed hunks inside it, so an
+// addition and a removal never conflict, and both are applied: only different
+// additions, or different removals, over the same region do. The union of
+// conflicting hunks is returned as conflicts, where merged keeps the base coverage.
+func ThreeWayMerge(base, a, b Segments) (merged Segments, conflicts Segments) {
+	type hunk struct {
+		seg   Segment
+		added bool
+	}
+	hunks := func(side Segments) []hunk {
+		var output []hunk
+		for _, s := range SetDiff(side, base) {
+			output = append(output, hunk{s, true})
+		}
+		for _, s := range SetDiff(base, side) {
+			output = append(output, hunk{s, false})
+		}
+		return output
+	}
+	hunksA, hunksB := hunks(a), hunks(b)
+	for _, ha := range hunksA {
+		for _, hb := range hunksB {
+			if ha.seg.OverlapLength(hb.seg) > 0 && ha != hb {
+				conflicts = append(conflicts, ha.seg, hb.seg)
+			}
+		}
+	}
+	conflicts = RemoveOverlaps(conflicts)
+
+	// Where a and b agree, take either one; otherwise one side kept the base
+	// coverage, so the point is covered if and only if the other side added it.
+	merged = Union(Intersect(a, b), SetDiff(a, base), SetDiff(b, base))
+	if conflicts != nil {
+		merged = Union(SetDiff(merged, conflicts), Intersect(base, conflicts))
+	}
+	return merged, conflicts
+}
+
+// Gaps returns the uncovered segments strictly between the first start and
+// the last end of RemoveOverlaps(ss), in increasing order.
+// Unlike Complement, it does not require a superset.
+// Empty or single-segment inputs have no gaps, so nil is returned.
+func Gaps(ss Segments) Segments {
+	var output Segments
+	normalized := RemoveOverlaps(ss)
+	for i := 1; i < len(normalized); i++ {
+		output = append(output, Segment{normalized[i-1].end, normalized[i].start})
+	}
+	return output
+}
+
+// Invert returns the complement of ss over the whole int64 range: the
+// unbounded tail {math.MinInt64, first start}, the Gaps of ss, and the
+// unbounded tail {last end, math.MaxInt64}, in increasing order.
+// A tail is omitted if ss already reaches the end of the range.
+// Empty input gives the single segment {math.MinInt64, math.MaxInt64}.
+func Invert(ss Segments) Segments {
+	normalized := RemoveOverlaps(ss)
+	if len(normalized) == 0 {
+		return Segments{Segment{math.MinInt64, math.MaxInt64}}
+	}
+	var output Segments
+	if first := normalized[0].start; first > math.MinInt64 {
+		output = append(output, Segment{math.MinInt64, first})
+	}
+	output = append(output, Gaps(normalized)...)
+	if last := normalized[len(normalized)-1].end; last < math.MaxInt64 {
+		output = append(output, Segment{last, math.MaxInt64})
+	}
+	return output
+}
+
+// LargestGap returns the longest gap between consecutive segments of
+// RemoveOverlaps(ss), and whether there is such a gap.
+// If several gaps have the same length, the first one is returned.
+// ok is false if RemoveOverlaps(ss) has fewer than two segments.
+func (ss Segments) LargestGap() (gap Segment, ok bool) {
+	return ss.extremeGap(func(g, best Segment) bool { return g.Delta() > best.Delta() })
+}
+
+// SmallestGap returns the shortest gap between consecutive segments of
+// RemoveOverlaps(ss), and whether there is such a gap.
+// If several gaps have the same length, the first one is returned.
+// ok is false if RemoveOverlaps(ss) has fewer than two segments.
+func (ss Segments) SmallestGap() (gap Segment, ok bool) {
+	return ss.extremeGap(func(g, best Segment) bool { return g.Delta() < best.Delta() })
+}
+
+// extremeGap returns the first gap of RemoveOverlaps(ss) such that no later gap
+// is better, as per the better function.
+func (ss Segments) extremeGap(better func(g, best Segment) bool) (Segment, bool) {
+	gaps := Gaps(ss)
+	if len(gaps) == 0 {
+		return Segment{}, false
+	}
+	best := gaps[0]
+	for _, g := range gaps[1:] {
+		if better(g, best) {
+			best = g
+		}
+	}
+	return best, true
+}
+
+// IsIntersectionEmpty returns whether the intersection between
+// a segment and a slice of segments is empty.
+// It is equivalent to len(Intersect(Segments{s}, tt)) == 0, but stops at the
+// first intersection found and does not allocate.
+func (s Segment) IsIntersectionEmpty(tt Segments) bool {
+	for _, t := range tt {
+		if s.Overlaps(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// IntersectWith returns the pieces of s which intersect ss, sorted by start.
+// It is equivalent to Intersect(Segments{s}, ss); unlike IsIntersectionEmpty,
+// it returns the intersection itself.
+func (s Segment) IntersectWith(ss Segments) Segments {
+	return Intersect(Segments{s}, ss)
+}
+
+// ContainmentForest returns the nesting structure of ss: for each index i of a
+// segment containing other segments, the increasing indices of the segments
+// directly nested in ss[i]. Each segment is nested in its innermost container:
+// among the other segments it is a sub-segment of, the one with the latest
+// start, and the shortest of those. When segments nest without crossing, as
+// spans or scopes do, this is the only container not nested in another one.
+// Equal segments are nested by index, the later one inside the earlier one.
+// Segments without nested segments have no entry. This takes O(n log n) time.
+func (ss Segments) ContainmentForest() map[int][]int {
+	// Containers come before the segments they contain in this order.
+	order := make([]int, len(ss))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(x, y int) bool {
+		s, t := ss[order[x]], ss[order[y]]
+		if s.start != t.start {
+			return s.start < t.start
+		}
+		if s.end != t.end {
+			return s.end > t.end
+		}
+		return order[x] < order[y]
+	})
+
+	// open holds the containers of the current segment, innermost last.
+	output := make(map[int][]int)
+	var open []int
+	for _, j := range order {
+		for len(open) > 0 && ss[open[len(open)-1]].end < ss[j].end {
+			open = open[:len(open)-1]
+		}
+		if len(open) > 0 {
+			parent := open[len(open)-1]
+			output[parent] = append(output[parent], j)
+		}
+		open = append(open, j)
+	}
+	for _, children := range output {
+		sort.Ints(children)
+	}
+	return output
+}
+
+// OverlapsAtLeast reports whether segments s and t intersect over a length of at least minLen.
+// Segments which only touch intersect over a length of 0, so they fail for any minLen > 0.
+func (s Segment) OverlapsAtLeast(t Segment, minLen int64) bool {
+	intersect, ok := SimpleIntersection(s, t)
+	return ok && intersect.Delta() >= minLen
+}
+
+// OverlapsAtLeast returns the indices of the segments of ss which intersect t
+// over a length of at least minLen, in increasing order.
+func (ss Segments) OverlapsAtLeast(t Segment, minLen int64) []int {
+	var output []int
+	for i, s := range ss {
+		if s.OverlapsAtLeast(t, minLen) {
+			output = append(output, i)
+		}
+	}
+	return output
+}
+
+// IsSubSegment reports whether segment s is a sub-segment of segment t.
+func (s Segment) IsSubSegment(t Segment) bool {
+	return s.start >= t.start && s.end <= t.end
+}
+
+// Contains reports whether segment t is a sub-segment of segment s.
+// It is the inverse of IsSubSegment, so a segment contains itself,
+// and any point segment lying within it.
+func (s Segment) Contains(t Segment) bool {
+	return t.IsSubSegment(s)
+}
+
+// Equal reports whether segments s and t have the same start and end.
+func (s Segment) Equal(t Segment) bool {
+	return s.start == t.start && s.end == t.end
+}
+
+// Equal reports whether ss and tt hold equal segments in the same order.
+// Unlike reflect.DeepEqual, a nil slice is equal to an empty one.
+func (ss Segments) Equal(tt Segments) bool {
+	if len(ss) != len(tt) {
+		return false
+	}
+	for i := range ss {
+		if !ss[i].Equal(tt[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// EqualWithin reports whether the starts of s and t, and their ends, differ by
+// at most tol, for example to compare segments rounded by LinearTransform.
+// If tol < 0, it returns false.
+func (s Segment) EqualWithin(t Segment, tol int64) bool {
+	return max(s.start-t.start, t.start-s.start) <= tol && max(s.end-t.end, t.end-s.end) <= tol
+}
+
+// EqualWithin reports whether ss and tt have the same length, and each segment
+// of ss is EqualWithin tol of the segment of tt at the same index.
+// As with Equal, a nil slice is equal to an empty one.
+func (ss Segments) EqualWithin(tt Segments, tol int64) bool {
+	if len(ss) != len(tt) {
+		return false
+	}
+	for i := range ss {
+		if !ss[i].EqualWithin(tt[i], tol) {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPointOnBoundary reports whether the point p is one of the endpoints of segment s.
+func (s Segment) IsPointOnBoundary(p int64) bool {
+	return p == s.start || p == s.end
+}
+
+// TightenTo returns the smallest sub-segment of s containing all the points
+// that lie in s, and whether any point lies in s. Points outside s are ignored.
+func (s Segment) TightenTo(points []int64) (Segment, bool) {
+	var output Segment
+	found := false
+	for _, p := range points {
+		if !IsPointInSegment(p, s) {
+			continue
+		}
+		if !found {
+			output, found = Segment{p, p}, true
+			continue
+		}
+		if p < output.start {
+			output.start = p
+		}
+		if p > output.end {
+			output.end = p
+		}
+	}
+	return output, found
+}
+
+// IsPointInSegments returns true if and only if the point is contained
+// in any of the segments in a slice of segments.
+func IsPointInSegments(p int64, ss Segments) bool {
+	for _, s := range ss {
+		if IsPointInSegment(p, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPointInSegment returns true if and only if the point is contained in the segment.
+// Both endpoints are contained; see IsPointInSegmentHalfOpen for half-open segments.
+func IsPointInSegment(p int64, s Segment) bool {
+	return s.start <= p && p <= s.end
+}
+
+// FindSegment returns the index of the segment of ss containing point p, and
+// whether there is one, using a binary search in O(log n).
+// ss must be sorted by start and not overlap, as returned by RemoveOverlaps;
+// this is not checked, and the result is unspecified otherwise.
+func (ss Segments) FindSegment(p int64) (int, bool) {
+	i := sort.Search(len(ss), func(i int) bool { return ss[i].end >= p })
+	if i < len(ss) && ss[i].start <= p {
+		return i, true
+	}
+	return -1, false
+}
+
+// ContainsPointSorted returns true if and only if the point p is contained in
+// any of the segments of ss, like IsPointInSegments, but in O(log n).
+// ss must be sorted by start and not overlap, as returned by RemoveOverlaps;
+// this is not checked, and the result is unspecified otherwise.
+func (ss Segments) ContainsPointSorted(p int64) bool {
+	_, ok := ss.FindSegment(p)
+	return ok
+}
+
+// ContainsPoints reports, for each point of ps, whether it is contained in any
+// of the segments of ss, like IsPointInSegments, in the order of ps. The points
+// are sorted and matched against RemoveOverlaps(ss) in a single merge pass, which
+// takes O(n log n + m log m) time rather than O(n * m). ps is not modified.
+func (ss Segments) ContainsPoints(ps []int64) []bool {
+	order := make([]int, len(ps))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool { return ps[order[i]] < ps[order[j]] })
+
+	output := make([]bool, len(ps))
+	normalized := RemoveOverlaps(ss)
+	j := 0
+	for _, i := range order {
+		p := ps[i]
+		for j < len(normalized) && normalized[j].end < p {
+			j++
+		}
+		output[i] = j < len(normalized) && normalized[j].start <= p
+	}
+	return output
+}
+
+// Nearest returns the segment of ss closest to point p, and the distance from p
+// to it: 0 if the segment contains p, and the gap to its nearest endpoint otherwise.
+// If several segments are equally close, the first one in ss is returned.
+// ss need not be sorted. If ss is empty, false is returned and the output segment is nil.
+func (ss Segments) Nearest(p int64) (Segment, int64, bool) {
+	var nearest Segment
+	var distance int64
+	for i, s := range ss {
+		d := max(s.start-p, p-s.end, 0)
+		if i == 0 || d < distance {
+			nearest, distance = s, d
+		}
+	}
+	return nearest, distance, len(ss) > 0
+}
+
+// CoverPoint returns ss grown to cover point p, for example to mark a timestamp
+// as seen. If p is already covered, ss is returned unchanged. Otherwise, the
+// first segment of ss adjacent to p, ending at p - 1 or starting at p + 1, is
+// extended to reach p, or the point segment {p, p} is added if none is adjacent,
+// and the result is returned with overlaps removed, as per RemoveOverlaps.
+// The input is not modified.
+func (ss Segments) CoverPoint(p int64) Segments {
+	if IsPointInSegments(p, ss) {
+		return ss
+	}
+	output := ss.Clone()
+	for i, s := range output {
+		// The differences are only compared when positive, so they cannot wrap around to 1.
+		if s.end < p && p-s.end == 1 {
+			output[i].end = p
+			return RemoveOverlaps(output)
+		}
+		if s.start > p && s.start-p == 1 {
+			output[i].start = p
+			return RemoveOverlaps(output)
+		}
+	}
+	return RemoveOverlaps(append(output, Segment{p, p}))
+}
+
+//////// COVERAGE DEPTH ////////
+
+// DepthRun is a segment annotated with how many segments of a set cover it.
+type DepthRun struct {
+	Seg   Segment
+	Depth int
+}
+
+// CoverageRLE returns the coverage depth of ss as a run-length encoding:
+// consecutive runs covering the whole span from the smallest start to the
+// largest end of ss, each annotated with how many segments of ss cover it.
+// Adjacent runs always have different depths, and uncovered gaps are runs of depth 0.
+// Zero-length segments cover no length, so they are ignored.
+func (ss Segments) CoverageRLE() []DepthRun {
+	type event struct {
+		at    int64
+		delta int
+	}
+	var events []event
+	for _, s := range ss {
+		if s.IsDeltaPositive() {
+			events = append(events, event{s.start, 1}, event{s.end, -1})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].at < events[j].at })
+
+	var output []DepthRun
+	depth := 0
+	for i := 0; i < len(events); {
+		at := events[i].at
+		for ; i < len(events) && events[i].at == at; i++ {
+			depth += events[i].delta
+		}
+		if i == len(events) {
+			break
+		}
+		run := Segment{at, events[i].at}
+		if n := len(output); n > 0 && output[n-1].Depth == depth {
+			output[n-1].Seg.end = run.end
+			continue
+		}
+		output = append(output, DepthRun{run, depth})
+	}
+	return output
+}
+
+// CoverageProfile returns the pieces of the region covered by ss, each annotated
+// with how many segments of ss cover it, sorted by start. It is CoverageRLE
+// without the uncovered gaps: adjacent pieces have different depths, and
+// zero-length segments are ignored.
+func CoverageProfile(ss Segments) []DepthRun {
+	var output []DepthRun
+	for _, run := range ss.CoverageRLE() {
+		if run.Depth > 0 {
+			output = append(output, run)
+		}
+	}
+	return output
+}
+
+// MaxOverlap returns the highest number of segments of ss covering a common
+// point, and the first segment, by start, over which that depth holds.
+// Segments are closed, so segments which only touch, such as {0, 5} and {5, 10},
+// both cover their shared endpoint, and point segments count at their single coordinate.
+// If ss is empty, it returns 0 and the nil segment.
+func MaxOverlap(ss Segments) (depth int, where Segment) {
+	type event struct {
+		at    int64
+		delta int
+	}
+	var events []event
+	for _, s := range ss {
+		events = append(events, event{s.start, 1}, event{s.end, -1})
+	}
+	// At equal positions, starts come before ends, so that touching segments overlap.
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at != events[j].at {
+			return events[i].at < events[j].at
+		}
+		return events[i].delta > events[j].delta
+	})
+
+	current, peak := 0, -1
+	for i, e := range events {
+		current += e.delta
+		if current > depth {
+			depth, peak = current, i
+		}
+	}
+	if peak < 0 {
+		return 0, Segment{}
+	}
+	// The depth can only go up at a start, so the event following the peak is
+	// an end, and the depth holds until then.
+	return depth, Segment{events[peak].at, events[peak+1].at}
+}
+
+// WeightedRun is a segment annotated with the sum of the weights of the
+// segments of a set covering it.
+type WeightedRun struct {
+	Seg    Segment
+	Weight float64
+}
+
+// WeightedCoverage partitions the region covered by ss at every endpoint, and
+// returns each covered piece with the sum of weight(s) over the segments s of ss
+// covering it. Adjacent pieces with exactly equal total weights are merged.
+// Uncovered gaps are not returned, and zero-length segments are ignored.
+func WeightedCoverage(ss Segments, weight func(Segment) float64) []WeightedRun {
+	type event struct {
+		at     int64
+		delta  int
+		weight float64
+	}
+	var events []event
+	for _, s := range ss {
+		if s.IsDeltaPositive() {
+			w := weight(s)
+			events = append(events, event{s.start, 1, w}, event{s.end, -1, -w})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].at < events[j].at })
+
+	var output []WeightedRun
+	depth, total := 0, 0.0
+	for i := 0; i < len(events); {
+		at := events[i].at
+		for ; i < len(events) && events[i].at == at; i++ {
+			depth += events[i].delta
+			total += events[i].weight
+		}
+		if depth == 0 {
+			// Reset the total so floating point error does not carry over a gap.
+			total = 0
+			continue
+		}
+		run := Segment{at, events[i].at}
+		if n := len(output); n > 0 && output[n-1].Seg.end == run.start && output[n-1].Weight == total {
+			output[n-1].Seg.end = run.end
+			continue
+		}
+		output = append(output, WeightedRun{run, total})
+	}
+	return output
+}
+
+// WeightedSegment is a segment carrying an integer weight, such as a rate.
+// As with Labeled, the segment is a named field, so that the methods of Segment,
+// such as MarshalJSON and GobEncode, do not drop the weight.
+type WeightedSegment struct {
+	Seg    Segment
+	Weight int64
+}
+
+// Flatten turns overlapping weighted segments into a step function: it partitions
+// the region covered by ws at every endpoint, and returns each covered piece
+// with the sum of the weights of the segments covering it, sorted by start.
+// As with WeightedCoverage, adjacent pieces with equal total weights are merged,
+// uncovered gaps are not returned, and zero-length segments are ignored.
+// All endpoints at the same position are processed together, so the output
+// does not depend on the order of ws.
+func Flatten(ws []WeightedSegment) []WeightedSegment {
+	type event struct {
+		at     int64
+		delta  int
+		weight int64
+	}
+	var events []event
+	for _, w := range ws {
+		if w.Seg.IsDeltaPositive() {
+			events = append(events, event{w.Seg.start, 1, w.Weight}, event{w.Seg.end, -1, -w.Weight})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].at < events[j].at })
+
+	var output []WeightedSegment
+	depth, total := 0, int64(0)
+	for i := 0; i < len(events); {
+		at := events[i].at
+		for ; i < len(events) && events[i].at == at; i++ {
+			depth += events[i].delta
+			total += events[i].weight
+		}
+		if depth == 0 {
+			continue
+		}
+		piece := Segment{at, events[i].at}
+		if n := len(output); n > 0 && output[n-1].Seg.end == piece.start && output[n-1].Weight == total {
+			output[n-1].Seg.end = piece.end
+			continue
+		}
+		output = append(output, WeightedSegment{piece, total})
+	}
+	return output
+}
+
+//////// HALF-OPEN SEGMENTS ////////
+
+// IsPointInSegmentHalfOpen returns true if and only if the point is contained in
+// the segment seen as the half-open interval [start, end), so that p == end is excluded.
+func IsPointInSegmentHalfOpen(p int64, s Segment) bool {
+	return s.start <= p && p < s.end
+}
+
+// RemoveOverlapsHalfOpen takes out overlapping areas in a slice of segments seen
+// as half-open intervals [start, end). Unlike RemoveOverlaps, segments which only
+// touch, such as {0, 5} and {5, 10}, do not overlap, so they are not merged.
+// Segments where start >= end contain no points, so they are discarded.
+// The output is sorted by start.
+func RemoveOverlapsHalfOpen(ss Segments) Segments {
+	ssSorted := append(Segments{}, ss...)
+	sort.Slice(ssSorted, func(i, j int) bool { return ssSorted[i].start < ssSorted[j].start })
+	var output Segments
+
+	for _, s := range ssSorted {
+		if s.start >= s.end {
+			continue
+		}
+		n := len(output)
+		if n == 0 || output[n-1].end <= s.start {
+			output = append(output, Segment{s.start, s.end})
+		} else if output[n-1].end < s.end {
+			output[n-1].end = s.end
+		}
+	}
+	return output
 }
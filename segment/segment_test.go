@@ -16,8 +16,11 @@ package segment
 
 import (
 	"math"
+	"math/bits"
+	"math/rand"
 	"reflect"
 	"testing"
+	"time"
 )
 
 // Please keep the order of test functions the same as
@@ -67,6 +70,68 @@ func TestSegmentsString(t *testing.T) {
 	}
 }
 
+func TestSegmentCompactString(t *testing.T) {
+	testCases := []struct {
+		input Segment
+		want  string
+	}{
+		{input: Segment{11, 13}, want: "11-13"},
+		{input: Segment{11, 11}, want: "11"},
+		{input: Segment{-5, -3}, want: "-5--3"},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.CompactString(); got != test.want {
+			t.Errorf("%s.CompactString() = %s, should be %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSegmentsCompactString(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  string
+	}{
+		{
+			input: Segments{
+				Segment{11, 13},
+				Segment{20, 25},
+				Segment{30, 30},
+			},
+			want: "11-13,20-25,30",
+		},
+		{
+			input: nil,
+			want:  "",
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.CompactString(); got != test.want {
+			t.Errorf("%s.CompactString() = %s, should be %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestDurationString(t *testing.T) {
+	testCases := []struct {
+		input Segment
+		unit  time.Duration
+		want  string
+	}{
+		{input: Segment{3, 5}, unit: time.Millisecond, want: "2ms"},
+		{input: Segment{1000, 2500}, unit: time.Millisecond, want: "1.5s"},
+		{input: Segment{0, 90}, unit: time.Minute, want: "1h30m0s"},
+		{input: Segment{7, 7}, unit: time.Second, want: "0s"},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.DurationString(test.unit); got != test.want {
+			t.Errorf("%s.DurationString(%s) = %s, should be %s", test.input, test.unit, got, test.want)
+		}
+	}
+}
+
 func TestNew(t *testing.T) {
 	testCases := []struct {
 		start, end int64
@@ -96,6 +161,44 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestNewStrict(t *testing.T) {
+	testCases := []struct {
+		start, end int64
+		want       Segment
+		wanterr    string
+	}{
+		{
+			start: 0,
+			end:   1,
+			want:  Segment{0, 1},
+		},
+		{
+			start:   313,
+			end:     313,
+			want:    Segment{},
+			wanterr: "end == start: nil segment returned",
+		},
+		{
+			start:   0,
+			end:     -1,
+			want:    Segment{},
+			wanterr: "end < start: nil segment returned",
+		},
+	}
+
+	for _, test := range testCases {
+		got, goterr := NewStrict(test.start, test.end)
+		goterrstr := ""
+		if goterr != nil {
+			goterrstr = goterr.Error()
+		}
+		if got != test.want || goterrstr != test.wanterr {
+			t.Errorf("NewStrict(%d, %d) = %s, %q, should be %s, %q",
+				test.start, test.end, got, goterrstr, test.want, test.wanterr)
+		}
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	testCases := []struct {
 		s, want    Segment
@@ -267,6 +370,327 @@ func TestSegmentsLinearTransform(t *testing.T) {
 	}
 }
 
+func TestShift(t *testing.T) {
+	s := Segment{1, 2}
+	s.Shift(-5)
+	if want := (Segment{-4, -3}); s != want {
+		t.Errorf("Segment{1, 2}.Shift(-5) = %s, should be %s", s, want)
+	}
+
+	// Large values are not exactly representable as float64, but Shift is exact.
+	ss := Segments{
+		Segment{1 << 60, 1<<60 + 1},
+		Segment{0, 3},
+	}
+	ss.Shift(5)
+	if want := (Segments{Segment{1<<60 + 5, 1<<60 + 6}, Segment{5, 8}}); !reflect.DeepEqual(ss, want) {
+		t.Errorf("Shift(5) = %s, should be %s", ss, want)
+	}
+}
+
+func TestScale(t *testing.T) {
+	s := Segment{2, 5}
+	if err := s.Scale(1.5); err != nil || s != (Segment{3, 8}) {
+		t.Errorf("Segment{2, 5}.Scale(1.5) = %s, %v, should be %s", s, err, Segment{3, 8})
+	}
+	if err := s.Scale(-1); err == nil || s != (Segment{3, 8}) {
+		t.Errorf("Scale(-1) = %s, %v, should leave %s unchanged with an error", s, err, Segment{3, 8})
+	}
+
+	ss := Segments{Segment{2, 5}, Segment{-2, 0}}
+	if err := ss.Scale(2); err != nil || !reflect.DeepEqual(ss, Segments{Segment{4, 10}, Segment{-4, 0}}) {
+		t.Errorf("Scale(2) = %s, %v, should be %s", ss, err, Segments{Segment{4, 10}, Segment{-4, 0}})
+	}
+	if err := ss.Scale(-2); err == nil {
+		t.Errorf("Scale(-2) should return an error")
+	}
+}
+
+func TestSegmentDilate(t *testing.T) {
+	testCases := []struct {
+		input  Segment
+		amount int64
+		want   Segment
+	}{
+		{input: Segment{3, 5}, amount: 2, want: Segment{1, 7}},
+		{input: Segment{3, 3}, amount: 0, want: Segment{3, 3}},
+		{input: Segment{3, 5}, amount: -1, want: Segment{3, 5}},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.Dilate(test.amount); got != test.want {
+			t.Errorf("%s.Dilate(%d) = %s, should be %s", test.input, test.amount, got, test.want)
+		}
+	}
+}
+
+func TestSegmentsDilate(t *testing.T) {
+	input := Segments{
+		Segment{10, 12},
+		Segment{0, 2},
+		Segment{4, 5},
+	}
+	want := Segments{Segment{-1, 6}, Segment{9, 13}}
+	if got := input.Dilate(1); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.Dilate(1) = %s, should be %s", input, got, want)
+	}
+	if want := (Segments{Segment{0, 2}, Segment{4, 5}, Segment{10, 12}}); !reflect.DeepEqual(input.Sorted(), want) {
+		t.Errorf("Dilate modified its input: %s", input)
+	}
+}
+
+func TestSegmentErode(t *testing.T) {
+	testCases := []struct {
+		input  Segment
+		amount int64
+		want   Segment
+		wantok bool
+	}{
+		{input: Segment{1, 7}, amount: 2, want: Segment{3, 5}, wantok: true},
+		{input: Segment{1, 5}, amount: 2, want: Segment{3, 3}, wantok: true},
+		{input: Segment{1, 4}, amount: 2, want: Segment{}, wantok: false},
+		{input: Segment{1, 4}, amount: -2, want: Segment{1, 4}, wantok: true},
+		{input: Segment{0, 10}, amount: math.MaxInt64, want: Segment{}, wantok: false},
+		{input: Segment{math.MinInt64, math.MaxInt64}, amount: math.MaxInt64, want: Segment{-1, 0}, wantok: true},
+		{input: Segment{math.MinInt64, math.MaxInt64}, amount: math.MaxInt64 - 1, want: Segment{-2, 1}, wantok: true},
+	}
+
+	for _, test := range testCases {
+		if got, gotok := test.input.Erode(test.amount); got != test.want || gotok != test.wantok {
+			t.Errorf("%s.Erode(%d) = %s, %t, should be %s, %t", test.input, test.amount, got, gotok, test.want, test.wantok)
+		}
+	}
+}
+
+func TestSegmentsErode(t *testing.T) {
+	input := Segments{
+		Segment{10, 20},
+		Segment{0, 1},
+		Segment{4, 6},
+	}
+	want := Segments{Segment{11, 19}, Segment{5, 5}}
+	if got := input.Erode(1); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.Erode(1) = %s, should be %s", input, got, want)
+	}
+}
+
+func TestSegmentMirror(t *testing.T) {
+	testCases := []struct {
+		input Segment
+		pivot int64
+		want  Segment
+	}{
+		{input: Segment{2, 5}, pivot: 0, want: Segment{-5, -2}},
+		{input: Segment{2, 5}, pivot: 10, want: Segment{15, 18}},
+		{input: Segment{3, 3}, pivot: 3, want: Segment{3, 3}},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.Mirror(test.pivot); got != test.want {
+			t.Errorf("%s.Mirror(%d) = %s, should be %s", test.input, test.pivot, got, test.want)
+		}
+		if got := test.input.Mirror(test.pivot).Mirror(test.pivot); got != test.input {
+			t.Errorf("%s.Mirror(%d).Mirror(%d) = %s, should be %s", test.input, test.pivot, test.pivot, got, test.input)
+		}
+	}
+}
+
+func TestSegmentsMirror(t *testing.T) {
+	input := Segments{
+		Segment{0, 2},
+		Segment{5, 8},
+		Segment{6, 7},
+	}
+	want := Segments{Segment{2, 5}, Segment{3, 4}, Segment{8, 10}}
+	if got := input.Mirror(5); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.Mirror(5) = %s, should be %s", input, got, want)
+	}
+}
+
+func TestRebase(t *testing.T) {
+	testCases := []struct {
+		input      Segments
+		want       Segments
+		wantOffset int64
+	}{
+		{
+			input:      Segments{Segment{1005, 1010}, Segment{1000, 1002}},
+			want:       Segments{Segment{5, 10}, Segment{0, 2}},
+			wantOffset: 1000,
+		},
+		{
+			input:      Segments{Segment{-7, -3}},
+			want:       Segments{Segment{0, 4}},
+			wantOffset: -7,
+		},
+		{
+			input:      Segments{Segment{1 << 60, 1<<60 + 1}},
+			want:       Segments{Segment{0, 1}},
+			wantOffset: 1 << 60,
+		},
+		{
+			input:      nil,
+			want:       nil,
+			wantOffset: 0,
+		},
+	}
+
+	for _, test := range testCases {
+		got, offset := test.input.Rebase()
+		if !reflect.DeepEqual(got, test.want) || offset != test.wantOffset {
+			t.Errorf("%s.Rebase() = %s, %d, should be %s, %d", test.input, got, offset, test.want, test.wantOffset)
+		}
+		got.Shift(offset)
+		if !got.Equal(test.input) {
+			t.Errorf("shifting %s.Rebase() by its offset gives %s, should be %s", test.input, got, test.input)
+		}
+	}
+}
+
+func TestProjectWith(t *testing.T) {
+	testCases := []struct {
+		description string
+		ss          Segments
+		mapEndpoint func(int64) int64
+		want        Segments
+	}{
+		{
+			description: "squaring positive endpoints",
+			ss: Segments{
+				Segment{3, 4},
+				Segment{1, 2},
+				Segment{2, 3},
+			},
+			mapEndpoint: func(p int64) int64 { return p * p },
+			want: Segments{
+				Segment{1, 16},
+			},
+		},
+		{
+			description: "non-monotonic map discards inverted segments",
+			ss: Segments{
+				Segment{1, 2},
+				Segment{5, 6},
+			},
+			mapEndpoint: func(p int64) int64 { return -p },
+			want:        nil,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.ss.ProjectWith(test.mapEndpoint); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: %s.ProjectWith() = %s, want %s", test.description, test.ss, got, test.want)
+		}
+	}
+}
+
+func TestSnapEndpoints(t *testing.T) {
+	testCases := []struct {
+		description string
+		input       Segments
+		tol         int64
+		want        Segments
+	}{
+		{
+			description: "near-coincident boundaries collapse to a shared value",
+			input: Segments{
+				Segment{0, 99},
+				Segment{101, 200},
+				Segment{100, 150},
+			},
+			tol: 2,
+			want: Segments{
+				Segment{0, 100},
+				Segment{100, 200},
+				Segment{100, 150},
+			},
+		},
+		{
+			description: "short segments collapse rather than invert",
+			input: Segments{
+				Segment{10, 11},
+				Segment{0, 10},
+			},
+			tol: 1,
+			want: Segments{
+				Segment{10, 10},
+				Segment{0, 10},
+			},
+		},
+		{
+			description: "zero tolerance leaves segments unchanged",
+			input: Segments{
+				Segment{0, 99},
+				Segment{101, 200},
+			},
+			tol: 0,
+			want: Segments{
+				Segment{0, 99},
+				Segment{101, 200},
+			},
+		},
+		{
+			description: "negative tolerance is treated as zero",
+			input: Segments{
+				Segment{0, 1},
+				Segment{5, 6},
+			},
+			tol: -1,
+			want: Segments{
+				Segment{0, 1},
+				Segment{5, 6},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := SnapEndpoints(test.input, test.tol); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: SnapEndpoints(%s, %d) = %s, want %s", test.description, test.input, test.tol, got, test.want)
+		}
+	}
+}
+
+func TestNormalizeToUnit(t *testing.T) {
+	testCases := []struct {
+		ss        Segments
+		reference Segment
+		want      []UnitInterval
+		wanterr   bool
+	}{
+		{
+			ss: Segments{
+				Segment{120, 150},
+				Segment{50, 110},
+				Segment{300, 400},
+				Segment{190, 250},
+			},
+			reference: Segment{100, 200},
+			want: []UnitInterval{
+				{0.2, 0.5},
+				{0, 0.1},
+				{0.9, 1},
+			},
+			wanterr: false,
+		},
+		{
+			ss: Segments{
+				Segment{120, 150},
+			},
+			reference: Segment{100, 100},
+			want:      nil,
+			wanterr:   true,
+		},
+	}
+
+	for _, test := range testCases {
+		got, goterr := test.ss.NormalizeToUnit(test.reference)
+		if !reflect.DeepEqual(got, test.want) || (goterr != nil) != test.wanterr {
+			t.Errorf("%s.NormalizeToUnit(%s) = %v, should be %v; got error? %t, want error? %t",
+				test.ss, test.reference, got, test.want, goterr != nil, test.wanterr)
+		}
+	}
+}
+
 func TestStarts(t *testing.T) {
 	testCases := []struct {
 		input Segments
@@ -361,50 +785,236 @@ func TestEnds(t *testing.T) {
 	}
 }
 
-func TestDelta(t *testing.T) {
+func TestBounds(t *testing.T) {
 	testCases := []struct {
-		input Segment
-		want  int64
+		input  Segments
+		want   Segment
+		wantok bool
 	}{
 		{
-			input: Segment{11, 13},
-			want:  2,
+			input: Segments{
+				Segment{5, 8},
+				Segment{-2, 1},
+				Segment{3, 12},
+			},
+			want:   Segment{-2, 12},
+			wantok: true,
 		},
 		{
-			input: Segment{313, 313},
-			want:  0,
+			input:  Segments{Segment{4, 4}},
+			want:   Segment{4, 4},
+			wantok: true,
+		},
+		{
+			input:  nil,
+			want:   Segment{},
+			wantok: false,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := test.input.Delta(); got != test.want {
-			t.Errorf("%s.Delta() = %d, should be %d", test.input, got, test.want)
+		if got, ok := test.input.Bounds(); got != test.want || ok != test.wantok {
+			t.Errorf("%s.Bounds() = %s, %t, should be %s, %t", test.input, got, ok, test.want, test.wantok)
 		}
 	}
 }
 
-func TestIsDeltaPositive(t *testing.T) {
+func TestCenters(t *testing.T) {
 	testCases := []struct {
-		input Segment
-		want  bool
+		input Segments
+		want  []int64
 	}{
 		{
-			input: Segment{1, 2},
-			want:  true,
+			input: Segments{
+				Segment{2, 4},
+				Segment{1, 2},
+				Segment{-5, -2},
+				Segment{7, 7},
+			},
+			want: []int64{3, 1, -4, 7},
 		},
 		{
-			input: Segment{1, 1},
-			want:  false,
+			input: Segments{
+				Segment{math.MaxInt64 - 2, math.MaxInt64},
+				Segment{math.MinInt64, math.MaxInt64},
+			},
+			want: []int64{math.MaxInt64 - 1, -1},
+		},
+		{
+			input: Segments{},
+			want:  nil,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := test.input.IsDeltaPositive(); got != test.want {
+		if got := test.input.Centers(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.Centers() = %v, should be %v", test.input, got, test.want)
+		}
+		var want Segments
+		for _, c := range test.want {
+			want = append(want, Segment{c, c})
+		}
+		if got := test.input.CenterSegments(); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s.CenterSegments() = %s, should be %s", test.input, got, want)
+		}
+	}
+}
+
+func TestPoints(t *testing.T) {
+	testCases := []struct {
+		s    Segment
+		step int64
+		want []int64
+	}{
+		{
+			s:    Segment{3, 5},
+			step: 1,
+			want: []int64{3, 4, 5},
+		},
+		{
+			s:    Segment{0, 10},
+			step: 4,
+			want: []int64{0, 4, 8},
+		},
+		{
+			s:    Segment{7, 7},
+			step: 3,
+			want: []int64{7},
+		},
+		{
+			s:    Segment{math.MaxInt64 - 3, math.MaxInt64},
+			step: 2,
+			want: []int64{math.MaxInt64 - 3, math.MaxInt64 - 1},
+		},
+		{
+			s:    Segment{math.MinInt64, math.MaxInt64},
+			step: math.MaxInt64,
+			want: []int64{math.MinInt64, -1, math.MaxInt64 - 1},
+		},
+		{
+			s:    Segment{0, 10},
+			step: 0,
+			want: nil,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.Points(test.step); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.Points(%d) = %v, should be %v", test.s, test.step, got, test.want)
+		}
+	}
+}
+
+func TestEach(t *testing.T) {
+	s := Segment{0, 10}
+	var got []int64
+	s.Each(2, func(p int64) bool {
+		got = append(got, p)
+		return p < 4
+	})
+	if want := []int64{0, 2, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.Each(2) stopping after 4 visited %v, should be %v", s, got, want)
+	}
+}
+
+func TestDelta(t *testing.T) {
+	testCases := []struct {
+		input Segment
+		want  int64
+	}{
+		{
+			input: Segment{11, 13},
+			want:  2,
+		},
+		{
+			input: Segment{313, 313},
+			want:  0,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.Delta(); got != test.want {
+			t.Errorf("%s.Delta() = %d, should be %d", test.input, got, test.want)
+		}
+	}
+}
+
+func TestDeltaChecked(t *testing.T) {
+	testCases := []struct {
+		input   Segment
+		want    int64
+		wanterr bool
+	}{
+		{input: Segment{1, 4}, want: 3, wanterr: false},
+		{input: Segment{math.MinInt64, -1}, want: math.MaxInt64, wanterr: false},
+		{input: Segment{math.MinInt64, 0}, want: 0, wanterr: true},
+		{input: Segment{math.MinInt64, math.MaxInt64}, want: 0, wanterr: true},
+		{input: Segment{math.MaxInt64, math.MinInt64}, want: 0, wanterr: true},
+		{input: Segment{5, 2}, want: -3, wanterr: false},
+	}
+
+	for _, test := range testCases {
+		got, goterr := test.input.DeltaChecked()
+		if got != test.want || (goterr != nil) != test.wanterr {
+			t.Errorf("%s.DeltaChecked() = %d, %v, should be %d; want error? %t", test.input, got, goterr, test.want, test.wanterr)
+		}
+	}
+}
+
+func TestIsDeltaPositive(t *testing.T) {
+	testCases := []struct {
+		input Segment
+		want  bool
+	}{
+		{
+			input: Segment{1, 2},
+			want:  true,
+		},
+		{
+			input: Segment{1, 1},
+			want:  false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.IsDeltaPositive(); got != test.want {
 			t.Errorf("%s.IsDeltaPositive() = %t, should be %t", test.input, got, test.want)
 		}
 	}
 }
 
+func TestAsTimeRange(t *testing.T) {
+	epoch := time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := Segment{-1500, 250}
+	start, end := s.AsTimeRange(epoch, time.Millisecond)
+	if want := time.Date(2018, 1, 2, 3, 4, 3, 500e6, time.UTC); !start.Equal(want) {
+		t.Errorf("%s.AsTimeRange(%s, %s) start = %s, should be %s", s, epoch, time.Millisecond, start, want)
+	}
+	if want := time.Date(2018, 1, 2, 3, 4, 5, 250e6, time.UTC); !end.Equal(want) {
+		t.Errorf("%s.AsTimeRange(%s, %s) end = %s, should be %s", s, epoch, time.Millisecond, end, want)
+	}
+}
+
+func TestPointCount(t *testing.T) {
+	testCases := []struct {
+		input Segment
+		want  int64
+	}{
+		{input: Segment{3, 5}, want: 3},
+		{input: Segment{313, 313}, want: 1},
+		{input: Segment{-2, 1}, want: 4},
+		{input: Segment{0, math.MaxInt64 - 1}, want: math.MaxInt64},
+		{input: Segment{0, math.MaxInt64}, want: math.MaxInt64},
+		{input: Segment{math.MinInt64, math.MaxInt64}, want: math.MaxInt64},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.PointCount(); got != test.want {
+			t.Errorf("%s.PointCount() = %d, should be %d", test.input, got, test.want)
+		}
+	}
+}
+
 func TestSumDeltas(t *testing.T) {
 	testCases := []struct {
 		input Segments
@@ -442,6 +1052,135 @@ func TestSumDeltas(t *testing.T) {
 	}
 }
 
+func TestCoveredLength(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  int64
+	}{
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{2, 8},
+				Segment{5, 15},
+			},
+			want: 15,
+		},
+		{
+			input: Segments{
+				Segment{2, 3},
+				Segment{1, 2},
+				Segment{4, 5},
+			},
+			want: 2 + 1,
+		},
+		{
+			input: nil,
+			want:  0,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.CoveredLength(); got != test.want {
+			t.Errorf("%s.CoveredLength() = %d, should be %d", test.input, got, test.want)
+		}
+	}
+}
+
+func TestTotalPointCount(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  int64
+	}{
+		{
+			input: Segments{
+				Segment{0, 2},
+				Segment{2, 4},
+			},
+			want: 5,
+		},
+		{
+			input: Segments{
+				Segment{3, 4},
+				Segment{0, 2},
+				Segment{1, 1},
+			},
+			want: 5,
+		},
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{20, 20},
+			},
+			want: 12,
+		},
+		{
+			input: Segments{
+				Segment{math.MinInt64, -1},
+				Segment{1, math.MaxInt64},
+			},
+			want: math.MaxInt64,
+		},
+		{
+			input: Segments{
+				Segment{math.MinInt64, 0},
+				Segment{0, math.MaxInt64},
+			},
+			want: math.MaxInt64,
+		},
+		{
+			input: nil,
+			want:  0,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.TotalPointCount(); got != test.want {
+			t.Errorf("%s.TotalPointCount() = %d, should be %d", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSamplePoint(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	if p, ok := (Segments{}).SamplePoint(r); ok {
+		t.Errorf("Segments{}.SamplePoint() = %d, true, want false", p)
+	}
+
+	// Covers the 5 points 0 to 4 (twice overlapping) and the single point 10,
+	// so each of the 6 points should be drawn about a sixth of the time.
+	ss := Segments{Segment{0, 3}, Segment{2, 4}, Segment{10, 10}}
+	const n = 60000
+	counts := make(map[int64]int)
+	for i := 0; i < n; i++ {
+		p, ok := ss.SamplePoint(r)
+		if !ok || !IsPointInSegments(p, ss) {
+			t.Fatalf("%s.SamplePoint() = %d, %t, want a covered point", ss, p, ok)
+		}
+		counts[p]++
+	}
+	if len(counts) != 6 {
+		t.Errorf("%s.SamplePoint() drew points %v, want all 6 covered points", ss, counts)
+	}
+	for p, c := range counts {
+		if c < n/6*9/10 || c > n/6*11/10 {
+			t.Errorf("%s.SamplePoint() drew %d %d times out of %d, want about %d", ss, p, c, n, n/6)
+		}
+	}
+
+	// Point counts beyond the int64 range are still sampled from.
+	for _, ss := range []Segments{
+		{Segment{0, math.MaxInt64}},
+		{Segment{math.MinInt64, math.MaxInt64}},
+		{Segment{math.MinInt64, -1}, Segment{1, math.MaxInt64}},
+	} {
+		for i := 0; i < 100; i++ {
+			if p, ok := ss.SamplePoint(r); !ok || !IsPointInSegments(p, ss) {
+				t.Fatalf("%s.SamplePoint() = %d, %t, want a covered point", ss, p, ok)
+			}
+		}
+	}
+}
+
 func TestSumDeltasUpToPoint(t *testing.T) {
 	testCases := []struct {
 		input Segments
@@ -499,730 +1238,4099 @@ func TestSumDeltasUpToPoint(t *testing.T) {
 	}
 }
 
-func TestSegmentsWithPredicate(t *testing.T) {
+func TestCoverageUpTo(t *testing.T) {
 	testCases := []struct {
-		predicateDescription string
-		ss                   Segments
-		pred                 func(s Segment) bool
-		want                 Segments
+		input                   Segments
+		limit                   int64
+		wantCovered, wantCutoff int64
 	}{
 		{
-			predicateDescription: "segment delta is positive",
-			ss: Segments{
-				Segment{2, 2},
-				Segment{1, 2}},
-			pred: func(s Segment) bool { return s.Delta() > 0 },
-			want: Segments{
-				Segment{1, 2}},
+			input: Segments{
+				Segment{10, 20},
+				Segment{0, 5},
+				Segment{3, 6},
+			},
+			limit:       10,
+			wantCovered: 10,
+			wantCutoff:  14,
 		},
 		{
-			predicateDescription: "segment delta is positive",
-			ss: Segments{
-				Segment{2, 2},
-				Segment{1, 1}},
-			pred: func(s Segment) bool { return s.Delta() > 0 },
-			want: nil,
+			input: Segments{
+				Segment{0, 5},
+				Segment{10, 20},
+			},
+			limit:       5,
+			wantCovered: 5,
+			wantCutoff:  5,
+		},
+		{
+			input: Segments{
+				Segment{0, 5},
+				Segment{10, 20},
+			},
+			limit:       100,
+			wantCovered: 15,
+			wantCutoff:  20,
+		},
+		{
+			input:       Segments{Segment{10, 20}},
+			limit:       0,
+			wantCovered: 0,
+			wantCutoff:  10,
+		},
+		{
+			input:       Segments{Segment{10, 20}},
+			limit:       -5,
+			wantCovered: 0,
+			wantCutoff:  10,
+		},
+		{
+			input:       nil,
+			limit:       100,
+			wantCovered: 0,
+			wantCutoff:  0,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := SegmentsWithPredicate(test.ss, test.pred); !reflect.DeepEqual(got, test.want) {
-			t.Errorf("SegmentsWithPredicate(%s, predicate: %s) = %s, want %s",
-				test.ss, test.predicateDescription, got, test.want)
+		if covered, cutoff := test.input.CoverageUpTo(test.limit); covered != test.wantCovered || cutoff != test.wantCutoff {
+			t.Errorf("%s.CoverageUpTo(%d) = %d, %d, want %d, %d",
+				test.input, test.limit, covered, cutoff, test.wantCovered, test.wantCutoff)
 		}
 	}
 }
 
-func TestRemoveOverlaps(t *testing.T) {
+func TestTakeLength(t *testing.T) {
+	input := Segments{
+		Segment{10, 20},
+		Segment{0, 5},
+		Segment{2, 4},
+	}
 	testCases := []struct {
-		input, want Segments
+		n    int64
+		want Segments
+	}{
+		{n: 8, want: Segments{Segment{0, 5}, Segment{10, 13}}},
+		{n: 5, want: Segments{Segment{0, 5}}},
+		{n: 3, want: Segments{Segment{0, 3}}},
+		{n: 100, want: Segments{Segment{0, 5}, Segment{10, 20}}},
+		{n: 0, want: nil},
+	}
+
+	for _, test := range testCases {
+		if got := input.TakeLength(test.n); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.TakeLength(%d) = %s, want %s", input, test.n, got, test.want)
+		}
+	}
+	if want := (Segments{Segment{10, 20}, Segment{0, 5}, Segment{2, 4}}); !reflect.DeepEqual(input, want) {
+		t.Errorf("TakeLength modified its input to %s", input)
+	}
+}
+
+func TestCoverageInWindow(t *testing.T) {
+	ss := Segments{
+		Segment{10, 20},
+		Segment{15, 25},
+		Segment{40, 50},
+	}
+	testCases := []struct {
+		window Segment
+		want   int64
 	}{
 		{
-			input: Segments{
-				Segment{2, 3},
-				Segment{1, 2},
-				Segment{4, 5},
-			},
-			want: Segments{
-				Segment{1, 3},
-				Segment{4, 5},
-			},
+			window: Segment{20, 45},
+			want:   5 + 5,
 		},
 		{
-			input: Segments{
-				Segment{0, 1},
-				Segment{-1, 5},
-				Segment{4, 6},
-			},
-			want: Segments{
-				Segment{-1, 6},
-			},
+			window: Segment{0, 100},
+			want:   15 + 10,
 		},
 		{
-			input: Segments{
-				Segment{3, 3},
-				Segment{4, 5},
-			},
-			want: Segments{
-				Segment{3, 3},
-				Segment{4, 5},
-			},
+			window: Segment{26, 39},
+			want:   0,
 		},
 		{
-			input: Segments{
-				Segment{int64(math.MinInt64), 3},
-				Segment{4, 5},
-			},
-			want: Segments{
-				Segment{4, 5},
-			},
+			window: Segment{12, 14},
+			want:   2,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := RemoveOverlaps(test.input); !reflect.DeepEqual(got, test.want) {
-			t.Errorf("RemoveOverlaps(%s) is %s, should be %s", test.input, got, test.want)
+		if got := ss.CoverageInWindow(test.window); got != test.want {
+			t.Errorf("%s.CoverageInWindow(%s) = %d, want %d", ss, test.window, got, test.want)
 		}
 	}
 }
 
-func TestUnionWithTwoInputs(t *testing.T) {
+func TestCoverageFraction(t *testing.T) {
 	testCases := []struct {
-		description string
-		x, y, want  Segments
+		superset Segment
+		ss       Segments
+		want     float64
 	}{
 		{
-			description: "x is empty",
-			x:           Segments{},
-			y: Segments{
-				Segment{1, 3},
-				Segment{2, 4},
-			},
-			want: Segments{
-				Segment{1, 4},
-			},
+			superset: Segment{0, 100},
+			ss:       Segments{Segment{-10, 50}, Segment{40, 60}, Segment{90, 200}},
+			want:     0.7,
 		},
 		{
-			description: "no overlap between x and y",
-			x: Segments{
-				Segment{1, 5},
-				Segment{2, 10},
-			},
-			y: Segments{
-				Segment{-5, -1},
-				Segment{-10, -2},
-			},
-			want: Segments{
-				Segment{-10, -1},
-				Segment{1, 10},
-			},
+			superset: Segment{0, 100},
+			ss:       Segments{Segment{-10, 200}},
+			want:     1,
 		},
 		{
-			description: "some overlap between x and y",
-			x: Segments{
-				Segment{1, 5},
-				Segment{2, 10},
-			},
-			y: Segments{
-				Segment{3, 12},
-				Segment{14, 15},
-			},
-			want: Segments{
-				Segment{1, 12},
-				Segment{14, 15},
-			},
+			superset: Segment{0, 100},
+			ss:       nil,
+			want:     0,
+		},
+		{
+			superset: Segment{5, 5},
+			ss:       Segments{Segment{0, 10}},
+			want:     0,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := Union(test.x, test.y); !reflect.DeepEqual(got, test.want) {
-			t.Errorf("%s: Union(%s, %s) = %s, want %s",
-				test.description, test.x, test.y, got, test.want)
+		if got := CoverageFraction(test.superset, test.ss); got != test.want {
+			t.Errorf("CoverageFraction(%s, %s) = %v, want %v", test.superset, test.ss, got, test.want)
 		}
 	}
 }
 
-func TestUnionWithVaryingNumberOfInputs(t *testing.T) {
-
-	// Test Union with nil input.
-	if got := Union(nil); got != nil {
-		t.Errorf("Union(nil) = %s, want nil", got)
-	}
-
-	s1 := Segments{
-		Segment{1, 3},
-		Segment{2, 4},
-	}
-	s2 := Segments{
-		Segment{-1, 2},
-		Segment{1, 2},
-	}
-	s3 := Segments{
-		Segment{8, 10},
-		Segment{-2, 0},
-	}
-
-	// Test Union with single slice input.
-	// Results should be the same as RemoveOverlaps.
-	if got, want := Union(s1), (Segments{Segment{1, 4}}); !reflect.DeepEqual(got, want) {
-		t.Errorf("Union(%s) = %s, want %s", s1, got, want)
-	}
-
-	// Test Union with three slices as input.
-	// Union(x, y, z) should be equivalent to Union(Union(x, y), z).
-	if got, want := Union(s1, s2, s3), (Segments{Segment{-2, 4}, Segment{8, 10}}); !reflect.DeepEqual(got, want) {
-		t.Errorf("Union(%s, %s, %s) = %s, want %s", s1, s2, s3, got, want)
-	}
-}
-
-func TestSimpleIntersection(t *testing.T) {
+func TestRecencyWeightedCoverage(t *testing.T) {
 	testCases := []struct {
-		s, t    Segment
-		wantSeg Segment
-		wantOk  bool
+		description string
+		input       Segments
+		now         int64
+		halfLife    int64
+		want        float64
 	}{
 		{
-			s:       Segment{2, 30},
-			t:       Segment{20, 40},
-			wantSeg: Segment{20, 30},
-			wantOk:  true,
-		},
-		{
-			s:       Segment{20, 40},
-			t:       Segment{2, 30},
-			wantSeg: Segment{20, 30},
-			wantOk:  true,
-		},
-		{
-			s:       Segment{-20, 40},
-			t:       Segment{2, 30},
-			wantSeg: Segment{2, 30},
-			wantOk:  true,
+			description: "segments one and two half-lives old",
+			input: Segments{
+				Segment{80, 90},
+				Segment{70, 80},
+			},
+			now:      100,
+			halfLife: 10,
+			want:     10*0.5 + 10*0.25,
 		},
 		{
-			s:       Segment{-20, 40},
-			t:       Segment{-200, 300},
-			wantSeg: Segment{-20, 40},
-			wantOk:  true,
+			description: "overlaps use the most recent weight",
+			input: Segments{
+				Segment{0, 100},
+				Segment{50, 150},
+			},
+			now:      150,
+			halfLife: 50,
+			want:     100*1 + 50*0.5,
 		},
 		{
-			s:      Segment{-20, 10},
-			t:      Segment{200, 300},
-			wantOk: false,
+			description: "segments ending after now have weight 1",
+			input: Segments{
+				Segment{0, 10},
+			},
+			now:      5,
+			halfLife: 1,
+			want:     10,
 		},
 		{
-			s:       Segment{0, 1},
-			t:       Segment{1, 2},
-			wantSeg: Segment{1, 1},
-			wantOk:  true,
+			description: "half-life is not positive",
+			input: Segments{
+				Segment{0, 10},
+			},
+			now:      10,
+			halfLife: 0,
+			want:     0,
 		},
 	}
 
 	for _, test := range testCases {
-		if gotSeg, gotOk := SimpleIntersection(test.s, test.t); gotOk != test.wantOk || (gotOk && !reflect.DeepEqual(gotSeg, test.wantSeg)) {
-			t.Errorf("SimpleIntersection(%s, %s) is %s, %t, expected %s, %t", test.s, test.t, gotSeg, gotOk, test.wantSeg, test.wantOk)
+		if got := test.input.RecencyWeightedCoverage(test.now, test.halfLife); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: %s.RecencyWeightedCoverage(%d, %d) = %f, want %f",
+				test.description, test.input, test.now, test.halfLife, got, test.want)
 		}
 	}
+
+	// A recent segment should count more than an older one of the same length.
+	recent, old := Segments{Segment{90, 100}}, Segments{Segment{10, 20}}
+	if r, o := recent.RecencyWeightedCoverage(100, 30), old.RecencyWeightedCoverage(100, 30); r <= o {
+		t.Errorf("recent segment weighs %f, not more than old segment weighing %f", r, o)
+	}
 }
 
-func TestIntersect(t *testing.T) {
+func TestSignature(t *testing.T) {
+	window := Segment{0, 640}
 	testCases := []struct {
-		description string
-		x, y, want  Segments
+		input Segments
+		bits  int
+		want  uint64
 	}{
 		{
-			description: "x is empty",
-			x:           Segments{},
-			y:           Segments{Segment{1, 5}},
-			want:        nil,
+			input: Segments{
+				Segment{0, 10},
+				Segment{25, 35},
+			},
+			bits: 4,
+			want: 0,
 		},
 		{
-			description: "y is empty",
-			x:           Segments{Segment{1, 5}},
-			y:           Segments{},
-			want:        nil,
+			input: Segments{
+				Segment{0, 100},
+				Segment{380, 600},
+			},
+			bits: 4,
+			want: 0b1101,
 		},
 		{
-			description: "no overlap between x and y",
-			x: Segments{
-				Segment{1, 5},
-				Segment{2, 10},
-			},
-			y: Segments{
-				Segment{-5, -1},
-				Segment{-10, -2},
+			input: Segments{
+				Segment{0, 640},
 			},
-			want: nil,
+			bits: 0,
+			want: 0,
 		},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.Signature(window, test.bits); got != test.want {
+			t.Errorf("%s.Signature(%s, %d) = %b, want %b", test.input, window, test.bits, got, test.want)
+		}
+	}
+
+	x := Segments{Segment{0, 200}, Segment{300, 500}}
+	nearX := Segments{Segment{0, 205}, Segment{295, 500}, Segment{600, 602}}
+	farX := Segments{Segment{200, 300}, Segment{500, 640}}
+	sig := x.Signature(window, 64)
+	if d := bits.OnesCount64(sig ^ nearX.Signature(window, 64)); d > 2 {
+		t.Errorf("near-identical sets have signatures at Hamming distance %d, want at most 2", d)
+	}
+	if d := bits.OnesCount64(sig ^ farX.Signature(window, 64)); d < 32 {
+		t.Errorf("dissimilar sets have signatures at Hamming distance %d, want at least 32", d)
+	}
+}
+
+func TestSlidingCoverage(t *testing.T) {
+	testCases := []struct {
+		description     string
+		input           Segments
+		windowLen, step int64
+		over            Segment
+		want            []CoverageSample
+	}{
 		{
-			description: "some overlap between x and y",
-			x: Segments{
-				Segment{1, 5},
-				Segment{2, 10},
-				Segment{12, 16},
-			},
-			y: Segments{
-				Segment{3, 7},
-				Segment{11, 15},
+			description: "constant coverage gives a flat profile",
+			input: Segments{
+				Segment{0, 100},
 			},
-			want: Segments{
-				Segment{3, 7},
-				Segment{12, 15},
+			windowLen: 10,
+			step:      10,
+			over:      Segment{20, 50},
+			want: []CoverageSample{
+				{20, 10},
+				{30, 10},
+				{40, 10},
+				{50, 10},
 			},
 		},
 		{
-			description: "infinitesimal point overlap between x and y",
-			x: Segments{
-				Segment{1, 5},
-				Segment{2, 10},
-				Segment{12, 16},
+			description: "transition from covered to uncovered",
+			input: Segments{
+				Segment{5, 20},
+				Segment{0, 10},
+				Segment{40, 42},
 			},
-			y: Segments{
-				Segment{3, 7},
-				Segment{16, 17},
+			windowLen: 10,
+			step:      5,
+			over:      Segment{10, 42},
+			want: []CoverageSample{
+				{10, 10},
+				{15, 10},
+				{20, 10},
+				{25, 5},
+				{30, 0},
+				{35, 0},
+				{40, 0},
 			},
-			want: Segments{
-				Segment{3, 7},
-				Segment{16, 16},
+		},
+		{
+			description: "step is not positive",
+			input: Segments{
+				Segment{0, 100},
 			},
+			windowLen: 10,
+			step:      0,
+			over:      Segment{20, 50},
+			want:      nil,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := Intersect(test.x, test.y); !reflect.DeepEqual(got, test.want) {
-			t.Errorf("%s: Intersect(%s, %s) = %s, want %s",
-				test.description, test.x, test.y, got, test.want)
+		got := test.input.SlidingCoverage(test.windowLen, test.step, test.over)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: %s.SlidingCoverage(%d, %d, %s) = %v, want %v",
+				test.description, test.input, test.windowLen, test.step, test.over, got, test.want)
+		}
+		// Check against computing each window from scratch.
+		for _, sample := range got {
+			if want := test.input.CoverageInWindow(Segment{sample.At - test.windowLen, sample.At}); sample.Coverage != want {
+				t.Errorf("%s: coverage at %d is %d, but CoverageInWindow gives %d",
+					test.description, sample.At, sample.Coverage, want)
+			}
 		}
 	}
 }
 
-func TestGetOverlaps(t *testing.T) {
+func TestBinCounts(t *testing.T) {
 	testCases := []struct {
-		s, want Segments
+		input    Segments
+		window   Segment
+		binWidth int64
+		want     []int
 	}{
 		{
-			s: Segments{
-				Segment{2, 30},
-				Segment{40, 50},
-				Segment{60, 80},
+			input: Segments{
+				Segment{50, 70},
+				Segment{10, 20},
+				Segment{20, 40},
+				Segment{100, 150},
+				Segment{125, 125},
 			},
-			want: nil,
+			window:   Segment{0, 125},
+			binWidth: 60,
+			want:     []int{3, 2, 1},
 		},
 		{
-			s: Segments{
-				Segment{2, 30},
-				Segment{10, 50},
-				Segment{60, 80},
-			},
-			want: Segments{
-				Segment{10, 30},
+			input: Segments{
+				Segment{0, 60},
+				Segment{60, 60},
 			},
+			window:   Segment{0, 120},
+			binWidth: 60,
+			want:     []int{1, 1},
 		},
 		{
-			s: Segments{
-				Segment{2, 30},
-				Segment{10, 50},
-				Segment{50, 80},
-			},
-			want: Segments{
-				Segment{10, 30},
-				Segment{50, 50},
+			input: Segments{
+				Segment{0, 60},
 			},
+			window:   Segment{0, 120},
+			binWidth: 0,
+			want:     nil,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := GetOverlaps(test.s); !reflect.DeepEqual(got, test.want) {
-			t.Errorf("GetOverlaps(%s) is %s, expected is %s", test.s, got, test.want)
+		if got := test.input.BinCounts(test.window, test.binWidth); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.BinCounts(%s, %d) = %v, want %v", test.input, test.window, test.binWidth, got, test.want)
 		}
 	}
 }
 
-func TestComplement(t *testing.T) {
+func TestSegmentsWithPredicate(t *testing.T) {
 	testCases := []struct {
-		description string
-		ss          Segments
-		superset    Segment
-		want        Segments
+		predicateDescription string
+		ss                   Segments
+		pred                 func(s Segment) bool
+		want                 Segments
 	}{
 		{
-			description: "set of segments is empty",
-			ss:          nil,
-			superset:    Segment{0, 100},
-			want: Segments{
-				Segment{0, 100},
-			},
-		},
-		{
-			description: "segments only contains a point segment",
+			predicateDescription: "segment delta is positive",
 			ss: Segments{
 				Segment{2, 2},
-			},
-			superset: Segment{0, 3},
+				Segment{1, 2}},
+			pred: func(s Segment) bool { return s.Delta() > 0 },
 			want: Segments{
-				Segment{0, 2},
-				Segment{2, 3},
-			},
+				Segment{1, 2}},
 		},
 		{
-			description: "superset is empty",
+			predicateDescription: "segment delta is positive",
 			ss: Segments{
-				Segment{1, 2},
-				Segment{3, 4},
-			},
-			superset: Segment{},
-			want:     nil,
+				Segment{2, 2},
+				Segment{1, 1}},
+			pred: func(s Segment) bool { return s.Delta() > 0 },
+			want: nil,
 		},
-		{
-			description: "superset is a true superset of the segments",
-			ss: Segments{
-				Segment{1, 3},
-				Segment{0, 2},
-				Segment{4, 6},
+	}
+
+	for _, test := range testCases {
+		if got := SegmentsWithPredicate(test.ss, test.pred); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SegmentsWithPredicate(%s, predicate: %s) = %s, want %s",
+				test.ss, test.predicateDescription, got, test.want)
+		}
+	}
+}
+
+func TestMapSegments(t *testing.T) {
+	input := Segments{
+		Segment{8, 15},
+		Segment{-5, 2},
+		Segment{20, 30},
+		Segment{4, 12},
+	}
+	window := Segment{0, 10}
+	clip := func(s Segment) (Segment, bool) { return SimpleIntersection(s, window) }
+	want := Segments{Segment{8, 10}, Segment{0, 2}, Segment{4, 10}}
+	if got := MapSegments(input, clip); !reflect.DeepEqual(got, want) {
+		t.Errorf("MapSegments(%s, clip to %s) = %s, want %s", input, window, got, want)
+	}
+
+	drop := func(s Segment) (Segment, bool) { return s, false }
+	if got := MapSegments(input, drop); got != nil {
+		t.Errorf("MapSegments(%s, drop) = %s, want nil", input, got)
+	}
+}
+
+func TestIsNormalized(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		ss := randomSegments(r, 10, 50, 10)
+		if got := RemoveOverlaps(ss); !got.IsNormalized() {
+			t.Errorf("RemoveOverlaps(%s) = %s is not normalized", ss, got)
+		}
+	}
+
+	ss := Segments{Segment{0, 2}, Segment{3, 3}, Segment{5, 8}}
+	if allocs := testing.AllocsPerRun(10, func() { ss.IsNormalized() }); allocs != 0 {
+		t.Errorf("IsNormalized() allocates %v times, want 0", allocs)
+	}
+}
+
+func TestIsSortedDisjoint(t *testing.T) {
+	testCases := []struct {
+		description                  string
+		input                        Segments
+		wantNormalized, wantTouching bool
+	}{
+		{
+			description:    "normalized",
+			input:          Segments{Segment{0, 2}, Segment{3, 3}, Segment{5, 8}},
+			wantNormalized: true,
+			wantTouching:   true,
+		},
+		{
+			description:    "unsorted",
+			input:          Segments{Segment{5, 8}, Segment{0, 2}},
+			wantNormalized: false,
+			wantTouching:   false,
+		},
+		{
+			description:    "overlapping",
+			input:          Segments{Segment{0, 5}, Segment{3, 8}},
+			wantNormalized: false,
+			wantTouching:   false,
+		},
+		{
+			description:    "touching",
+			input:          Segments{Segment{0, 5}, Segment{5, 8}},
+			wantNormalized: false,
+			wantTouching:   true,
+		},
+		{
+			description:    "end < start",
+			input:          Segments{Segment{5, 0}},
+			wantNormalized: false,
+			wantTouching:   false,
+		},
+		{
+			description:    "empty",
+			input:          nil,
+			wantNormalized: true,
+			wantTouching:   true,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.IsNormalized(); got != test.wantNormalized {
+			t.Errorf("%s: %s.IsNormalized() = %t, want %t", test.description, test.input, got, test.wantNormalized)
+		}
+		if got := test.input.IsSortedDisjoint(true); got != test.wantTouching {
+			t.Errorf("%s: %s.IsSortedDisjoint(true) = %t, want %t", test.description, test.input, got, test.wantTouching)
+		}
+	}
+}
+
+func TestPruneShort(t *testing.T) {
+	input := Segments{
+		Segment{0, 1},
+		Segment{5, 10},
+		Segment{2, 4},
+		Segment{3, 3},
+	}
+	if got, want := PruneShort(input, 2), (Segments{Segment{5, 10}, Segment{2, 4}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("PruneShort(%s, 2) = %s, want %s", input, got, want)
+	}
+	if got := PruneShort(input, 0); !reflect.DeepEqual(got, input) {
+		t.Errorf("PruneShort(%s, 0) = %s, want %s", input, got, input)
+	}
+}
+
+func TestClone(t *testing.T) {
+	ss := Segments{Segment{1, 2}, Segment{5, 8}}
+	clone := ss.Clone()
+	if !reflect.DeepEqual(clone, ss) {
+		t.Errorf("%s.Clone() = %s, want %s", ss, clone, ss)
+	}
+	clone.Shift(10)
+	if want := (Segments{Segment{1, 2}, Segment{5, 8}}); !reflect.DeepEqual(ss, want) {
+		t.Errorf("modifying %s.Clone() modified it to %s", want, ss)
+	}
+
+	if got := Segments(nil).Clone(); got != nil {
+		t.Errorf("Segments(nil).Clone() = %#v, want nil", got)
+	}
+	if got := (Segments{}).Clone(); got == nil || len(got) != 0 {
+		t.Errorf("Segments{}.Clone() = %#v, want Segments{}", got)
+	}
+}
+
+func TestSort(t *testing.T) {
+	testCases := []struct {
+		input, want Segments
+	}{
+		{
+			input: Segments{
+				Segment{3, 8},
+				Segment{1, 2},
+				Segment{3, 5},
+				Segment{0, 10},
+				Segment{3, 5},
 			},
-			superset: Segment{-10, 6},
 			want: Segments{
-				Segment{-10, 0},
-				Segment{3, 4},
+				Segment{0, 10},
+				Segment{1, 2},
+				Segment{3, 5},
+				Segment{3, 5},
+				Segment{3, 8},
 			},
 		},
 		{
-			description: "superset.end lies within a segment",
-			ss: Segments{
-				Segment{0, 2},
-				Segment{4, 6},
+			input: nil,
+			want:  nil,
+		},
+	}
+
+	for _, test := range testCases {
+		inputCopy := append(Segments(nil), test.input...)
+		if got := test.input.Sorted(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.Sorted() = %s, want %s", test.input, got, test.want)
+		}
+		if !reflect.DeepEqual(test.input, inputCopy) {
+			t.Errorf("Sorted() modified its input from %s to %s", inputCopy, test.input)
+		}
+		test.input.Sort()
+		if !reflect.DeepEqual(test.input, test.want) {
+			t.Errorf("%s.Sort() gives %s, want %s", inputCopy, test.input, test.want)
+		}
+	}
+}
+
+func TestInsertionIndex(t *testing.T) {
+	ss := Segments{
+		Segment{0, 2},
+		Segment{3, 5},
+		Segment{3, 8},
+		Segment{10, 12},
+	}
+	testCases := []struct {
+		s    Segment
+		want int
+	}{
+		{
+			s:    Segment{-1, 0},
+			want: 0,
+		},
+		{
+			s:    Segment{3, 6},
+			want: 2,
+		},
+		{
+			s:    Segment{3, 5},
+			want: 2,
+		},
+		{
+			s:    Segment{4, 4},
+			want: 3,
+		},
+		{
+			s:    Segment{11, 20},
+			want: 4,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := ss.InsertionIndex(test.s); got != test.want {
+			t.Errorf("%s.InsertionIndex(%s) = %d, want %d", ss, test.s, got, test.want)
+		}
+	}
+
+	if got := (Segments{}).InsertionIndex(Segment{1, 2}); got != 0 {
+		t.Errorf("Segments{}.InsertionIndex(%s) = %d, want 0", Segment{1, 2}, got)
+	}
+}
+
+func TestSegmentSplit(t *testing.T) {
+	testCases := []struct {
+		s                   Segment
+		p                   int64
+		wantLeft, wantRight Segment
+		wantOk              bool
+	}{
+		{
+			s:         Segment{0, 10},
+			p:         4,
+			wantLeft:  Segment{0, 4},
+			wantRight: Segment{4, 10},
+			wantOk:    true,
+		},
+		{
+			s:         Segment{0, 10},
+			p:         0,
+			wantLeft:  Segment{0, 0},
+			wantRight: Segment{0, 10},
+			wantOk:    true,
+		},
+		{
+			s:         Segment{0, 10},
+			p:         10,
+			wantLeft:  Segment{0, 10},
+			wantRight: Segment{10, 10},
+			wantOk:    true,
+		},
+		{
+			s:      Segment{0, 10},
+			p:      11,
+			wantOk: false,
+		},
+	}
+
+	for _, test := range testCases {
+		left, right, ok := test.s.Split(test.p)
+		if left != test.wantLeft || right != test.wantRight || ok != test.wantOk {
+			t.Errorf("%s.Split(%d) = %s, %s, %t, want %s, %s, %t",
+				test.s, test.p, left, right, ok, test.wantLeft, test.wantRight, test.wantOk)
+		}
+	}
+}
+
+func TestSegmentsSplit(t *testing.T) {
+	ss := Segments{
+		Segment{0, 10},
+		Segment{20, 30},
+		Segment{5, 5},
+		Segment{5, 8},
+		Segment{2, 6},
+	}
+	want := Segments{
+		Segment{0, 5},
+		Segment{5, 10},
+		Segment{20, 30},
+		Segment{5, 5},
+		Segment{5, 8},
+		Segment{2, 5},
+		Segment{5, 6},
+	}
+	if got := ss.Split(5); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.Split(5) = %s, want %s", ss, got, want)
+	}
+}
+
+func TestToGridCells(t *testing.T) {
+	testCases := []struct {
+		s        Segment
+		cellSize int64
+		want     Segments
+	}{
+		{
+			s:        Segment{3, 12},
+			cellSize: 5,
+			want: Segments{
+				Segment{3, 5},
+				Segment{5, 10},
+				Segment{10, 12},
 			},
-			superset: Segment{-10, 5},
+		},
+		{
+			s:        Segment{5, 10},
+			cellSize: 5,
 			want: Segments{
-				Segment{-10, 0},
-				Segment{2, 4},
+				Segment{5, 10},
 			},
 		},
 		{
-			description: "superset.end lies between segments",
-			ss: Segments{
-				Segment{4, 6},
+			s:        Segment{-7, 2},
+			cellSize: 5,
+			want: Segments{
+				Segment{-7, -5},
+				Segment{-5, 0},
 				Segment{0, 2},
 			},
-			superset: Segment{-10, 3},
+		},
+		{
+			s:        Segment{4, 4},
+			cellSize: 5,
 			want: Segments{
-				Segment{-10, 0},
+				Segment{4, 4},
+			},
+		},
+		{
+			s:        Segment{math.MaxInt64 - 3, math.MaxInt64},
+			cellSize: 10,
+			want: Segments{
+				Segment{math.MaxInt64 - 3, math.MaxInt64},
+			},
+		},
+		{
+			s:        Segment{math.MaxInt64 - 10, math.MaxInt64},
+			cellSize: 10,
+			want: Segments{
+				Segment{math.MaxInt64 - 10, math.MaxInt64 - 7},
+				Segment{math.MaxInt64 - 7, math.MaxInt64},
+			},
+		},
+		{
+			s:        Segment{math.MinInt64, math.MinInt64 + 10},
+			cellSize: 10,
+			want: Segments{
+				Segment{math.MinInt64, math.MinInt64 + 8},
+				Segment{math.MinInt64 + 8, math.MinInt64 + 10},
+			},
+		},
+		{
+			s:        Segment{3, 12},
+			cellSize: 0,
+			want:     nil,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.ToGridCells(test.cellSize); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.ToGridCells(%d) = %s, want %s", test.s, test.cellSize, got, test.want)
+		}
+	}
+}
+
+func TestSegmentChunk(t *testing.T) {
+	testCases := []struct {
+		input Segment
+		size  int64
+		want  Segments
+	}{
+		{input: Segment{0, 10}, size: 4, want: Segments{Segment{0, 4}, Segment{4, 8}, Segment{8, 10}}},
+		{input: Segment{3, 11}, size: 4, want: Segments{Segment{3, 7}, Segment{7, 11}}},
+		{input: Segment{3, 5}, size: 4, want: Segments{Segment{3, 5}}},
+		{input: Segment{3, 3}, size: 4, want: Segments{Segment{3, 3}}},
+		{input: Segment{math.MaxInt64 - 5, math.MaxInt64}, size: 4,
+			want: Segments{Segment{math.MaxInt64 - 5, math.MaxInt64 - 1}, Segment{math.MaxInt64 - 1, math.MaxInt64}}},
+		{input: Segment{0, 10}, size: 0, want: nil},
+	}
+
+	for _, test := range testCases {
+		got := test.input.Chunk(test.size)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.Chunk(%d) = %s, want %s", test.input, test.size, got, test.want)
+		}
+		if got != nil && !reflect.DeepEqual(Union(got), Segments{test.input}) {
+			t.Errorf("Union(%s.Chunk(%d)) = %s, want %s", test.input, test.size, Union(got), test.input)
+		}
+	}
+}
+
+func TestSegmentsChunk(t *testing.T) {
+	input := Segments{Segment{10, 15}, Segment{0, 2}}
+	want := Segments{Segment{10, 13}, Segment{13, 15}, Segment{0, 2}}
+	if got := input.Chunk(3); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.Chunk(3) = %s, want %s", input, got, want)
+	}
+	if got := input.Chunk(-1); got != nil {
+		t.Errorf("%s.Chunk(-1) = %s, want nil", input, got)
+	}
+}
+
+func TestSegmentSnapToGrid(t *testing.T) {
+	testCases := []struct {
+		input Segment
+		grid  int64
+		want  Segment
+	}{
+		{input: Segment{3, 12}, grid: 5, want: Segment{0, 15}},
+		{input: Segment{5, 10}, grid: 5, want: Segment{5, 10}},
+		{input: Segment{-7, -3}, grid: 5, want: Segment{-10, 0}},
+		{input: Segment{-7, 3}, grid: 5, want: Segment{-10, 5}},
+		{input: Segment{7, 7}, grid: 5, want: Segment{5, 10}},
+		{input: Segment{0, math.MaxInt64 - 3}, grid: 10, want: Segment{0, math.MaxInt64}},
+		{input: Segment{0, math.MaxInt64 - 7}, grid: 10, want: Segment{0, math.MaxInt64 - 7}},
+		{input: Segment{math.MinInt64 + 1, 0}, grid: 10, want: Segment{math.MinInt64, 0}},
+		{input: Segment{3, 12}, grid: 0, want: Segment{3, 12}},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.SnapToGrid(test.grid); got != test.want {
+			t.Errorf("%s.SnapToGrid(%d) = %s, want %s", test.input, test.grid, got, test.want)
+		}
+	}
+}
+
+func TestSegmentsSnapToGrid(t *testing.T) {
+	input := Segments{
+		Segment{21, 22},
+		Segment{1, 4},
+		Segment{6, 9},
+		Segment{-4, -1},
+	}
+	want := Segments{Segment{-5, 10}, Segment{20, 25}}
+	if got := input.SnapToGrid(5); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.SnapToGrid(5) = %s, want %s", input, got, want)
+	}
+}
+
+func TestRemoveOverlaps(t *testing.T) {
+	testCases := []struct {
+		input, want Segments
+	}{
+		{
+			input: Segments{
 				Segment{2, 3},
+				Segment{1, 2},
+				Segment{4, 5},
+			},
+			want: Segments{
+				Segment{1, 3},
+				Segment{4, 5},
 			},
 		},
 		{
-			description: "superset lies completely below all segments",
-			ss: Segments{
-				Segment{0, 2},
+			input: Segments{
+				Segment{0, 1},
+				Segment{-1, 5},
 				Segment{4, 6},
 			},
-			superset: Segment{-10, -1},
 			want: Segments{
-				Segment{-10, -1},
+				Segment{-1, 6},
 			},
 		},
 		{
-			description: "superset.start lies within a segment",
-			ss: Segments{
-				Segment{0, 2},
-				Segment{4, 6},
+			input: Segments{
+				Segment{3, 3},
+				Segment{4, 5},
+			},
+			want: Segments{
+				Segment{3, 3},
+				Segment{4, 5},
+			},
+		},
+		{
+			input: Segments{
+				Segment{4, 5},
+				Segment{int64(math.MinInt64), 3},
+			},
+			want: Segments{
+				Segment{int64(math.MinInt64), 3},
+				Segment{4, 5},
+			},
+		},
+		{
+			input: Segments{
+				Segment{int64(math.MinInt64), 3},
+				Segment{int64(math.MinInt64), 4},
+			},
+			want: Segments{
+				Segment{int64(math.MinInt64), 4},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := RemoveOverlaps(test.input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("RemoveOverlaps(%s) is %s, should be %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestMergeSorted(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  Segments
+	}{
+		{
+			input: Segments{
+				Segment{0, 5},
+				Segment{1, 2},
+				Segment{4, 10},
+				Segment{10, 12},
+				Segment{20, 25},
+			},
+			want: Segments{
+				Segment{0, 12},
+				Segment{20, 25},
+			},
+		},
+		{
+			input: Segments{
+				Segment{int64(math.MinInt64), 3},
+				Segment{4, 5},
+			},
+			want: Segments{
+				Segment{int64(math.MinInt64), 3},
+				Segment{4, 5},
+			},
+		},
+		{
+			input: nil,
+			want:  nil,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := MergeSorted(test.input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("MergeSorted(%s) = %s, want %s", test.input, got, test.want)
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		ss := randomSegments(r, 10, 50, 10)
+		ss.Sort()
+		if got, want := MergeSorted(ss), RemoveOverlaps(ss); !reflect.DeepEqual(got, want) {
+			t.Errorf("MergeSorted(%s) = %s, but RemoveOverlaps gives %s", ss, got, want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	ss := Segments{
+		Segment{20, 25},
+		Segment{4, 10},
+		Segment{0, 5},
+		Segment{10, 12},
+	}
+	backing := &ss[0]
+	ss.Normalize()
+	if want := (Segments{Segment{0, 12}, Segment{20, 25}}); !reflect.DeepEqual(ss, want) {
+		t.Errorf("Normalize() = %s, want %s", ss, want)
+	}
+	if &ss[0] != backing {
+		t.Errorf("Normalize() did not reuse the backing array")
+	}
+
+	var empty Segments
+	empty.Normalize()
+	if len(empty) != 0 {
+		t.Errorf("Normalize() on an empty slice = %s, want empty", empty)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		ss := randomSegments(r, 10, 50, 10)
+		want := RemoveOverlaps(ss)
+		got := ss.Clone()
+		got.Normalize()
+		if !got.Equal(want) {
+			t.Errorf("%s.Normalize() = %s, but RemoveOverlaps gives %s", ss, got, want)
+		}
+	}
+}
+
+func TestRemoveOverlapsCounted(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  []CountedSegment
+	}{
+		{
+			input: Segments{
+				Segment{20, 25},
+				Segment{0, 5},
+				Segment{4, 10},
+				Segment{1, 2},
+			},
+			want: []CountedSegment{
+				{Segment{0, 10}, 3},
+				{Segment{20, 25}, 1},
+			},
+		},
+		{
+			input: Segments{
+				Segment{int64(math.MinInt64), 3},
+				Segment{4, 5},
+			},
+			want: []CountedSegment{
+				{Segment{int64(math.MinInt64), 3}, 1},
+				{Segment{4, 5}, 1},
+			},
+		},
+		{
+			input: nil,
+			want:  nil,
+		},
+	}
+
+	for _, test := range testCases {
+		got := RemoveOverlapsCounted(test.input)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("RemoveOverlapsCounted(%s) = %v, want %v", test.input, got, test.want)
+		}
+		var geometry Segments
+		for _, c := range got {
+			geometry = append(geometry, c.Seg)
+		}
+		if want := RemoveOverlaps(test.input); !reflect.DeepEqual(geometry, want) {
+			t.Errorf("RemoveOverlapsCounted(%s) has segments %s, but RemoveOverlaps gives %s", test.input, geometry, want)
+		}
+	}
+}
+
+func TestRemoveOverlapsWithGroups(t *testing.T) {
+	testCases := []struct {
+		input      Segments
+		want       Segments
+		wantGroups [][]int
+	}{
+		{
+			input: Segments{
+				Segment{20, 25},
+				Segment{4, 10},
+				Segment{0, 5},
+				Segment{10, 12},
+				Segment{30, 30},
+			},
+			want: Segments{
+				Segment{0, 12},
+				Segment{20, 25},
+				Segment{30, 30},
+			},
+			wantGroups: [][]int{{1, 2, 3}, {0}, {4}},
+		},
+		{
+			input:      nil,
+			want:       nil,
+			wantGroups: nil,
+		},
+	}
+
+	for _, test := range testCases {
+		got, groups := RemoveOverlapsWithGroups(test.input)
+		if !reflect.DeepEqual(got, test.want) || !reflect.DeepEqual(groups, test.wantGroups) {
+			t.Errorf("RemoveOverlapsWithGroups(%s) = %s, %v, want %s, %v", test.input, got, groups, test.want, test.wantGroups)
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		ss := randomSegments(r, 10, 50, 10)
+		got, groups := RemoveOverlapsWithGroups(ss)
+		if want := RemoveOverlaps(ss); !reflect.DeepEqual(got, want) {
+			t.Errorf("RemoveOverlapsWithGroups(%s) = %s, but RemoveOverlaps gives %s", ss, got, want)
+		}
+		// Each group merges back into its output segment.
+		for k, g := range groups {
+			var members Segments
+			for _, i := range g {
+				members = append(members, ss[i])
+			}
+			if merged := RemoveOverlaps(members); !reflect.DeepEqual(merged, Segments{got[k]}) {
+				t.Errorf("RemoveOverlapsWithGroups(%s) group %v merges into %s, want %s", ss, g, merged, got[k])
+			}
+		}
+	}
+}
+
+func TestUnionWithTwoInputs(t *testing.T) {
+	testCases := []struct {
+		description string
+		x, y, want  Segments
+	}{
+		{
+			description: "x is empty",
+			x:           Segments{},
+			y: Segments{
+				Segment{1, 3},
+				Segment{2, 4},
+			},
+			want: Segments{
+				Segment{1, 4},
+			},
+		},
+		{
+			description: "no overlap between x and y",
+			x: Segments{
+				Segment{1, 5},
+				Segment{2, 10},
+			},
+			y: Segments{
+				Segment{-5, -1},
+				Segment{-10, -2},
+			},
+			want: Segments{
+				Segment{-10, -1},
+				Segment{1, 10},
+			},
+		},
+		{
+			description: "some overlap between x and y",
+			x: Segments{
+				Segment{1, 5},
+				Segment{2, 10},
+			},
+			y: Segments{
+				Segment{3, 12},
+				Segment{14, 15},
+			},
+			want: Segments{
+				Segment{1, 12},
+				Segment{14, 15},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := Union(test.x, test.y); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Union(%s, %s) = %s, want %s",
+				test.description, test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestUnionWithVaryingNumberOfInputs(t *testing.T) {
+
+	// Test Union with nil input.
+	if got := Union(nil); got != nil {
+		t.Errorf("Union(nil) = %s, want nil", got)
+	}
+
+	s1 := Segments{
+		Segment{1, 3},
+		Segment{2, 4},
+	}
+	s2 := Segments{
+		Segment{-1, 2},
+		Segment{1, 2},
+	}
+	s3 := Segments{
+		Segment{8, 10},
+		Segment{-2, 0},
+	}
+
+	// Test Union with single slice input.
+	// Results should be the same as RemoveOverlaps.
+	if got, want := Union(s1), (Segments{Segment{1, 4}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Union(%s) = %s, want %s", s1, got, want)
+	}
+
+	// Test Union with three slices as input.
+	// Union(x, y, z) should be equivalent to Union(Union(x, y), z).
+	if got, want := Union(s1, s2, s3), (Segments{Segment{-2, 4}, Segment{8, 10}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Union(%s, %s, %s) = %s, want %s", s1, s2, s3, got, want)
+	}
+}
+
+// randomSegment returns a random segment with a start in [-span/2, span/2)
+// and a length in [0, maxLen], for randomized tests.
+func randomSegment(r *rand.Rand, span, maxLen int64) Segment {
+	start := r.Int63n(span) - span/2
+	return Segment{start, start + r.Int63n(maxLen+1)}
+}
+
+// randomSegments returns up to maxN random segments, as per randomSegment,
+// in no particular order and possibly overlapping.
+func randomSegments(r *rand.Rand, maxN int, span, maxLen int64) Segments {
+	var ss Segments
+	for i := r.Intn(maxN + 1); i > 0; i-- {
+		ss = append(ss, randomSegment(r, span, maxLen))
+	}
+	return ss
+}
+
+// fuzzSegments decodes fuzzer input into segments, two bytes per segment:
+// a signed start, and an unsigned length, so every segment has start <= end.
+func fuzzSegments(data []byte) Segments {
+	var ss Segments
+	for i := 0; i+1 < len(data); i += 2 {
+		start := int64(int8(data[i]))
+		ss = append(ss, Segment{start, start + int64(data[i+1])})
+	}
+	return ss
+}
+
+// fuzzSeed is the inverse of fuzzSegments, to build a seed corpus from table tests.
+// Starts must fit in an int8 and lengths in a byte.
+func fuzzSeed(ss Segments) []byte {
+	var data []byte
+	for _, s := range ss {
+		data = append(data, byte(int8(s.start)), byte(s.Delta()))
+	}
+	return data
+}
+
+func FuzzUnion(f *testing.F) {
+	f.Add(fuzzSeed(Segments{}), fuzzSeed(Segments{Segment{1, 3}, Segment{2, 4}}), fuzzSeed(nil))
+	f.Add(fuzzSeed(Segments{Segment{1, 5}, Segment{2, 10}}), fuzzSeed(Segments{Segment{-5, -1}, Segment{-10, -2}}), fuzzSeed(nil))
+	f.Add(fuzzSeed(Segments{Segment{1, 5}, Segment{2, 10}}), fuzzSeed(Segments{Segment{3, 12}, Segment{14, 15}}),
+		fuzzSeed(Segments{Segment{-5, 0}, Segment{12, 14}}))
+	f.Fuzz(func(t *testing.T, x, y, z []byte) {
+		a, b, c := fuzzSegments(x), fuzzSegments(y), fuzzSegments(z)
+		ab := Union(a, b)
+		if ba := Union(b, a); !ab.Equal(ba) {
+			t.Errorf("Union(%s, %s) = %s, but Union(%s, %s) = %s", a, b, ab, b, a, ba)
+		}
+		abc := Union(a, b, c)
+		if got := Union(ab, c); !got.Equal(abc) {
+			t.Errorf("Union(Union(%s, %s), %s) = %s, but Union(%s, %s, %s) = %s", a, b, c, got, a, b, c, abc)
+		}
+		if got := Union(a, Union(b, c)); !got.Equal(abc) {
+			t.Errorf("Union(%s, Union(%s, %s)) = %s, but Union(%s, %s, %s) = %s", a, b, c, got, a, b, c, abc)
+		}
+		if !abc.IsNormalized() {
+			t.Errorf("Union(%s, %s, %s) = %s is not normalized", a, b, c, abc)
+		}
+	})
+}
+
+func TestConcatStrict(t *testing.T) {
+	testCases := []struct {
+		description string
+		slices      []Segments
+		want        Segments
+		wanterr     string
+	}{
+		{
+			description: "disjoint shards",
+			slices: []Segments{
+				{Segment{20, 29}, Segment{0, 9}},
+				{Segment{10, 19}},
+			},
+			want: Segments{
+				Segment{0, 9},
+				Segment{10, 19},
+				Segment{20, 29},
+			},
+		},
+		{
+			description: "overlaps within a shard are allowed",
+			slices: []Segments{
+				{Segment{0, 9}, Segment{5, 12}},
+				{Segment{20, 29}},
+			},
+			want: Segments{
+				Segment{0, 9},
+				Segment{5, 12},
+				Segment{20, 29},
+			},
+		},
+		{
+			description: "overlapping shards",
+			slices: []Segments{
+				{Segment{0, 9}},
+				{Segment{30, 39}},
+				{Segment{20, 29}, Segment{8, 12}},
+			},
+			want:    nil,
+			wanterr: "segment [start: 0, end: 9] from slice 0 overlaps segment [start: 8, end: 12] from slice 2: segments not concatenated",
+		},
+	}
+
+	for _, test := range testCases {
+		got, goterr := ConcatStrict(test.slices...)
+		goterrString := ""
+		if goterr != nil {
+			goterrString = goterr.Error()
+		}
+		if !reflect.DeepEqual(got, test.want) || goterrString != test.wanterr {
+			t.Errorf("%s: ConcatStrict(%v) = %s, %q, want %s, %q",
+				test.description, test.slices, got, goterrString, test.want, test.wanterr)
+		}
+	}
+}
+
+func TestMergeCapped(t *testing.T) {
+	testCases := []struct {
+		description string
+		input       Segments
+		maxLen      int64
+		want        Segments
+	}{
+		{
+			description: "overlapping run is split at the cap",
+			input: Segments{
+				Segment{6, 9},
+				Segment{0, 3},
+				Segment{4, 7},
+				Segment{2, 5},
+			},
+			maxLen: 5,
+			want: Segments{
+				Segment{0, 5},
+				Segment{5, 9},
+			},
+		},
+		{
+			description: "cap is never reached",
+			input: Segments{
+				Segment{0, 3},
+				Segment{3, 4},
+				Segment{10, 12},
+			},
+			maxLen: 100,
+			want: Segments{
+				Segment{0, 4},
+				Segment{10, 12},
+			},
+		},
+		{
+			description: "contained segments are absorbed and long segments are kept",
+			input: Segments{
+				Segment{0, 20},
+				Segment{5, 6},
+			},
+			maxLen: 5,
+			want: Segments{
+				Segment{0, 20},
+			},
+		},
+		{
+			description: "segments with the same start, shortest first",
+			input: Segments{
+				Segment{0, 2},
+				Segment{0, 10},
+			},
+			maxLen: 5,
+			want: Segments{
+				Segment{0, 10},
+			},
+		},
+		{
+			description: "segments with the same start, longest first",
+			input: Segments{
+				Segment{0, 10},
+				Segment{0, 2},
+			},
+			maxLen: 5,
+			want: Segments{
+				Segment{0, 10},
+			},
+		},
+		{
+			description: "negative cap is treated as zero",
+			input: Segments{
+				Segment{0, 3},
+				Segment{2, 5},
+			},
+			maxLen: -1,
+			want: Segments{
+				Segment{0, 3},
+				Segment{3, 5},
+			},
+		},
+		{
+			description: "empty input",
+			input:       nil,
+			maxLen:      5,
+			want:        nil,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := MergeCapped(test.input, test.maxLen); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: MergeCapped(%s, %d) = %s, want %s",
+				test.description, test.input, test.maxLen, got, test.want)
+		}
+	}
+}
+
+func TestMergeWithTolerance(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		gap   int64
+		want  Segments
+	}{
+		{
+			input: Segments{
+				Segment{12, 20},
+				Segment{0, 10},
+			},
+			gap: 3,
+			want: Segments{
+				Segment{0, 20},
+			},
+		},
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{20, 30},
+			},
+			gap: 3,
+			want: Segments{
+				Segment{0, 10},
+				Segment{20, 30},
+			},
+		},
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{10, 15},
+				Segment{16, 20},
+				Segment{2, 3},
+			},
+			gap: 0,
+			want: Segments{
+				Segment{0, 15},
+				Segment{16, 20},
+			},
+		},
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{10, 15},
+			},
+			gap: -5,
+			want: Segments{
+				Segment{0, 15},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := MergeWithTolerance(test.input, test.gap); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("MergeWithTolerance(%s, %d) = %s, want %s", test.input, test.gap, got, test.want)
+		}
+		if test.gap == 0 {
+			if want := RemoveOverlaps(test.input); !reflect.DeepEqual(test.want, want) {
+				t.Errorf("MergeWithTolerance(%s, 0) = %s, but RemoveOverlaps gives %s", test.input, test.want, want)
+			}
+		}
+	}
+}
+
+func TestSimpleIntersection(t *testing.T) {
+	testCases := []struct {
+		s, t    Segment
+		wantSeg Segment
+		wantOk  bool
+	}{
+		{
+			s:       Segment{2, 30},
+			t:       Segment{20, 40},
+			wantSeg: Segment{20, 30},
+			wantOk:  true,
+		},
+		{
+			s:       Segment{20, 40},
+			t:       Segment{2, 30},
+			wantSeg: Segment{20, 30},
+			wantOk:  true,
+		},
+		{
+			s:       Segment{-20, 40},
+			t:       Segment{2, 30},
+			wantSeg: Segment{2, 30},
+			wantOk:  true,
+		},
+		{
+			s:       Segment{-20, 40},
+			t:       Segment{-200, 300},
+			wantSeg: Segment{-20, 40},
+			wantOk:  true,
+		},
+		{
+			s:      Segment{-20, 10},
+			t:      Segment{200, 300},
+			wantOk: false,
+		},
+		{
+			s:       Segment{0, 1},
+			t:       Segment{1, 2},
+			wantSeg: Segment{1, 1},
+			wantOk:  true,
+		},
+		{
+			s:       Segment{5, 5},
+			t:       Segment{0, 10},
+			wantSeg: Segment{5, 5},
+			wantOk:  true,
+		},
+		{
+			s:      Segment{0, 1},
+			t:      Segment{2, 2},
+			wantOk: false,
+		},
+	}
+
+	for _, test := range testCases {
+		if gotSeg, gotOk := SimpleIntersection(test.s, test.t); gotOk != test.wantOk || (gotOk && !reflect.DeepEqual(gotSeg, test.wantSeg)) {
+			t.Errorf("SimpleIntersection(%s, %s) is %s, %t, expected %s, %t", test.s, test.t, gotSeg, gotOk, test.wantSeg, test.wantOk)
+		}
+		// SimpleIntersection should be symmetric.
+		if gotSeg, gotOk := SimpleIntersection(test.t, test.s); gotOk != test.wantOk || (gotOk && !reflect.DeepEqual(gotSeg, test.wantSeg)) {
+			t.Errorf("SimpleIntersection(%s, %s) is %s, %t, expected %s, %t", test.t, test.s, gotSeg, gotOk, test.wantSeg, test.wantOk)
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	testCases := []struct {
+		s, t Segment
+		want bool
+	}{
+		{s: Segment{0, 5}, t: Segment{3, 10}, want: true},
+		{s: Segment{0, 5}, t: Segment{5, 10}, want: true},
+		{s: Segment{0, 10}, t: Segment{3, 3}, want: true},
+		{s: Segment{0, 5}, t: Segment{6, 10}, want: false},
+		{s: Segment{6, 10}, t: Segment{0, 5}, want: false},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.Overlaps(test.t); got != test.want {
+			t.Errorf("%s.Overlaps(%s) = %t, want %t", test.s, test.t, got, test.want)
+		}
+		if _, ok := SimpleIntersection(test.s, test.t); ok != test.want {
+			t.Errorf("SimpleIntersection(%s, %s) returned %t, but Overlaps is %t", test.s, test.t, ok, test.want)
+		}
+	}
+}
+
+func TestOverlapLength(t *testing.T) {
+	testCases := []struct {
+		s, t Segment
+		want int64
+	}{
+		{s: Segment{0, 5}, t: Segment{3, 10}, want: 2},
+		{s: Segment{3, 10}, t: Segment{0, 5}, want: 2},
+		{s: Segment{0, 10}, t: Segment{3, 6}, want: 3},
+		{s: Segment{0, 5}, t: Segment{5, 10}, want: 0},
+		{s: Segment{0, 5}, t: Segment{8, 10}, want: 0},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.OverlapLength(test.t); got != test.want {
+			t.Errorf("%s.OverlapLength(%s) = %d, want %d", test.s, test.t, got, test.want)
+		}
+	}
+}
+
+func TestIsAdjacent(t *testing.T) {
+	testCases := []struct {
+		description string
+		s, t        Segment
+		want        bool
+	}{
+		{description: "touching", s: Segment{0, 5}, t: Segment{5, 10}, want: true},
+		{description: "touching, reversed", s: Segment{5, 10}, t: Segment{0, 5}, want: true},
+		{description: "overlapping", s: Segment{0, 5}, t: Segment{4, 10}, want: false},
+		{description: "disjoint", s: Segment{0, 5}, t: Segment{6, 10}, want: false},
+		{description: "point at an endpoint", s: Segment{0, 5}, t: Segment{5, 5}, want: true},
+		{description: "point inside", s: Segment{0, 10}, t: Segment{5, 5}, want: false},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.IsAdjacent(test.t); got != test.want {
+			t.Errorf("%s: %s.IsAdjacent(%s) = %t, want %t", test.description, test.s, test.t, got, test.want)
+		}
+	}
+}
+
+func TestSegmentClamp(t *testing.T) {
+	bounds := Segment{0, 10}
+	testCases := []struct {
+		s      Segment
+		want   Segment
+		wantOk bool
+	}{
+		{
+			s:      Segment{-5, 5},
+			want:   Segment{0, 5},
+			wantOk: true,
+		},
+		{
+			s:      Segment{2, 3},
+			want:   Segment{2, 3},
+			wantOk: true,
+		},
+		{
+			s:      Segment{10, 15},
+			want:   Segment{10, 10},
+			wantOk: true,
+		},
+		{
+			s:      Segment{11, 15},
+			wantOk: false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got, ok := test.s.Clamp(bounds); ok != test.wantOk || (ok && got != test.want) {
+			t.Errorf("%s.Clamp(%s) = %s, %t, want %s, %t", test.s, bounds, got, ok, test.want, test.wantOk)
+		}
+	}
+}
+
+func TestSegmentsClamp(t *testing.T) {
+	bounds := Segment{0, 10}
+	ss := Segments{
+		Segment{8, 20},
+		Segment{-5, -1},
+		Segment{-5, 0},
+		Segment{3, 4},
+	}
+	want := Segments{
+		Segment{8, 10},
+		Segment{0, 0},
+		Segment{3, 4},
+	}
+	if got := ss.Clamp(bounds); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.Clamp(%s) = %s, want %s", ss, bounds, got, want)
+	}
+}
+
+func TestStitch(t *testing.T) {
+	testCases := []struct {
+		description     string
+		a, b            Segment
+		wantResult      Segment
+		wantGap         Segment
+		wantOverlapping bool
+	}{
+		{
+			description:     "overlapping segments",
+			a:               Segment{0, 5},
+			b:               Segment{3, 8},
+			wantResult:      Segment{0, 8},
+			wantGap:         Segment{},
+			wantOverlapping: true,
+		},
+		{
+			description:     "touching segments",
+			a:               Segment{5, 8},
+			b:               Segment{0, 5},
+			wantResult:      Segment{0, 8},
+			wantGap:         Segment{},
+			wantOverlapping: true,
+		},
+		{
+			description:     "nested segments",
+			a:               Segment{0, 10},
+			b:               Segment{2, 3},
+			wantResult:      Segment{0, 10},
+			wantGap:         Segment{},
+			wantOverlapping: true,
+		},
+		{
+			description:     "gapped segments",
+			a:               Segment{10, 12},
+			b:               Segment{0, 5},
+			wantResult:      Segment{0, 12},
+			wantGap:         Segment{5, 10},
+			wantOverlapping: false,
+		},
+	}
+
+	for _, test := range testCases {
+		result, gap, overlapping := Stitch(test.a, test.b)
+		if result != test.wantResult || gap != test.wantGap || overlapping != test.wantOverlapping {
+			t.Errorf("%s: Stitch(%s, %s) = %s, %s, %t, want %s, %s, %t",
+				test.description, test.a, test.b, result, gap, overlapping,
+				test.wantResult, test.wantGap, test.wantOverlapping)
+		}
+	}
+}
+
+// intersectTestCases are the fixtures of TestIntersect, which TestCompare3Way
+// also uses to check a sweep built on Compare3Way.
+var intersectTestCases = []struct {
+	description string
+	x, y, want  Segments
+}{
+	{
+		description: "x is empty",
+		x:           Segments{},
+		y:           Segments{Segment{1, 5}},
+		want:        nil,
+	},
+	{
+		description: "y is empty",
+		x:           Segments{Segment{1, 5}},
+		y:           Segments{},
+		want:        nil,
+	},
+	{
+		description: "no overlap between x and y",
+		x: Segments{
+			Segment{1, 5},
+			Segment{2, 10},
+		},
+		y: Segments{
+			Segment{-5, -1},
+			Segment{-10, -2},
+		},
+		want: nil,
+	},
+	{
+		description: "some overlap between x and y",
+		x: Segments{
+			Segment{1, 5},
+			Segment{2, 10},
+			Segment{12, 16},
+		},
+		y: Segments{
+			Segment{3, 7},
+			Segment{11, 15},
+		},
+		want: Segments{
+			Segment{3, 7},
+			Segment{12, 15},
+		},
+	},
+	{
+		description: "infinitesimal point overlap between x and y",
+		x: Segments{
+			Segment{1, 5},
+			Segment{2, 10},
+			Segment{12, 16},
+		},
+		y: Segments{
+			Segment{3, 7},
+			Segment{16, 17},
+		},
+		want: Segments{
+			Segment{3, 7},
+			Segment{16, 16},
+		},
+	},
+}
+
+func TestCompare3Way(t *testing.T) {
+	testCases := []struct {
+		s, t           Segment
+		wantOverlap    Segment
+		wantHasOverlap bool
+		wantAdvance    int
+	}{
+		{
+			s:              Segment{1, 5},
+			t:              Segment{3, 7},
+			wantOverlap:    Segment{3, 5},
+			wantHasOverlap: true,
+			wantAdvance:    -1,
+		},
+		{
+			s:              Segment{12, 16},
+			t:              Segment{3, 7},
+			wantHasOverlap: false,
+			wantAdvance:    1,
+		},
+		{
+			s:              Segment{2, 7},
+			t:              Segment{3, 7},
+			wantOverlap:    Segment{3, 7},
+			wantHasOverlap: true,
+			wantAdvance:    0,
+		},
+		{
+			s:              Segment{12, 16},
+			t:              Segment{16, 17},
+			wantOverlap:    Segment{16, 16},
+			wantHasOverlap: true,
+			wantAdvance:    -1,
+		},
+	}
+
+	for _, test := range testCases {
+		overlap, hasOverlap, advance := test.s.Compare3Way(test.t)
+		if hasOverlap != test.wantHasOverlap || (hasOverlap && overlap != test.wantOverlap) || advance != test.wantAdvance {
+			t.Errorf("%s.Compare3Way(%s) = %s, %t, %d, want %s, %t, %d", test.s, test.t,
+				overlap, hasOverlap, advance, test.wantOverlap, test.wantHasOverlap, test.wantAdvance)
+		}
+	}
+
+	// A two-pointer sweep driven by Compare3Way over normalized inputs computes
+	// their intersection.
+	for _, test := range intersectTestCases {
+		x, y := RemoveOverlaps(test.x), RemoveOverlaps(test.y)
+		var got Segments
+		for i, j := 0, 0; i < len(x) && j < len(y); {
+			overlap, hasOverlap, advance := x[i].Compare3Way(y[j])
+			if hasOverlap {
+				got = append(got, overlap)
+			}
+			if advance <= 0 {
+				i++
+			}
+			if advance >= 0 {
+				j++
+			}
+		}
+		if want := Intersect(test.x, test.y); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: Compare3Way sweep over %s, %s = %s, but Intersect gives %s",
+				test.description, test.x, test.y, got, want)
+		}
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	for _, test := range intersectTestCases {
+		if got := Intersect(test.x, test.y); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Intersect(%s, %s) = %s, want %s",
+				test.description, test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestIntersectWithVaryingNumberOfInputs(t *testing.T) {
+
+	// Test Intersect with no input.
+	if got := Intersect(); got != nil {
+		t.Errorf("Intersect() = %s, want nil", got)
+	}
+
+	s1 := Segments{
+		Segment{1, 3},
+		Segment{2, 10},
+	}
+	s2 := Segments{
+		Segment{-1, 2},
+		Segment{5, 12},
+	}
+	s3 := Segments{
+		Segment{8, 10},
+		Segment{-2, 1},
+	}
+
+	// Test Intersect with single slice input.
+	// Results should be the same as RemoveOverlaps.
+	if got, want := Intersect(s1), (Segments{Segment{1, 10}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Intersect(%s) = %s, want %s", s1, got, want)
+	}
+
+	// Test Intersect with three slices as input.
+	// Intersect(x, y, z) should be equivalent to Intersect(Intersect(x, y), z).
+	got := Intersect(s1, s2, s3)
+	if want := (Segments{Segment{1, 1}, Segment{8, 10}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Intersect(%s, %s, %s) = %s, want %s", s1, s2, s3, got, want)
+	}
+	if want := Intersect(Intersect(s1, s2), s3); !reflect.DeepEqual(got, want) {
+		t.Errorf("Intersect(%s, %s, %s) = %s, but Intersect(Intersect(x, y), z) = %s", s1, s2, s3, got, want)
+	}
+}
+
+func FuzzIntersect(f *testing.F) {
+	f.Add(fuzzSeed(Segments{}), fuzzSeed(Segments{Segment{1, 5}}), fuzzSeed(nil))
+	f.Add(fuzzSeed(Segments{Segment{1, 5}, Segment{2, 10}, Segment{12, 16}}), fuzzSeed(Segments{Segment{3, 7}, Segment{11, 15}}), fuzzSeed(nil))
+	f.Add(fuzzSeed(Segments{Segment{1, 5}, Segment{2, 10}, Segment{12, 16}}), fuzzSeed(Segments{Segment{3, 7}, Segment{16, 17}}), fuzzSeed(nil))
+	f.Add(fuzzSeed(Segments{Segment{1, 3}, Segment{2, 10}}), fuzzSeed(Segments{Segment{-1, 2}, Segment{5, 12}}),
+		fuzzSeed(Segments{Segment{8, 10}, Segment{-2, 1}}))
+	f.Fuzz(func(t *testing.T, x, y, z []byte) {
+		a, b, c := fuzzSegments(x), fuzzSegments(y), fuzzSegments(z)
+		ab := Intersect(a, b)
+		if ba := Intersect(b, a); !ab.Equal(ba) {
+			t.Errorf("Intersect(%s, %s) = %s, but Intersect(%s, %s) = %s", a, b, ab, b, a, ba)
+		}
+		abc := Intersect(a, b, c)
+		if got := Intersect(a, Intersect(b, c)); !got.Equal(abc) {
+			t.Errorf("Intersect(%s, Intersect(%s, %s)) = %s, but Intersect(%s, %s, %s) = %s", a, b, c, got, a, b, c, abc)
+		}
+		// The intersection is a subset of both inputs.
+		if got, want := Union(a, ab), RemoveOverlaps(a); !got.Equal(want) {
+			t.Errorf("Intersect(%s, %s) = %s is not a subset of %s", a, b, ab, a)
+		}
+		if got, want := Union(b, ab), RemoveOverlaps(b); !got.Equal(want) {
+			t.Errorf("Intersect(%s, %s) = %s is not a subset of %s", a, b, ab, b)
+		}
+	})
+}
+
+func TestGetOverlaps(t *testing.T) {
+	testCases := []struct {
+		s, want Segments
+	}{
+		{
+			s: Segments{
+				Segment{2, 30},
+				Segment{40, 50},
+				Segment{60, 80},
+			},
+			want: nil,
+		},
+		{
+			s: Segments{
+				Segment{2, 30},
+				Segment{10, 50},
+				Segment{60, 80},
+			},
+			want: Segments{
+				Segment{10, 30},
+			},
+		},
+		{
+			s: Segments{
+				Segment{2, 30},
+				Segment{10, 50},
+				Segment{50, 80},
+			},
+			want: Segments{
+				Segment{10, 30},
+				Segment{50, 50},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := GetOverlaps(test.s); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("GetOverlaps(%s) is %s, expected is %s", test.s, got, test.want)
+		}
+	}
+}
+
+func TestOverlapLengthHistogram(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  map[int64]int
+	}{
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{8, 20},
+				Segment{30, 40},
+				Segment{38, 50},
+				Segment{60, 70},
+			},
+			want: map[int64]int{2: 2},
+		},
+		{
+			input: Segments{
+				Segment{5, 10},
+				Segment{0, 5},
+				Segment{0, 20},
+			},
+			want: map[int64]int{0: 1, 5: 2},
+		},
+		{
+			input: Segments{
+				Segment{0, 1},
+				Segment{2, 3},
+			},
+			want: map[int64]int{},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.OverlapLengthHistogram(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.OverlapLengthHistogram() = %v, want %v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestOverlapEdges(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  [][2]int
+	}{
+		{
+			input: Segments{
+				Segment{20, 30},
+				Segment{0, 10},
+				Segment{5, 25},
+				Segment{40, 50},
+				Segment{10, 12},
+			},
+			want: [][2]int{{0, 2}, {1, 2}, {1, 4}, {2, 4}},
+		},
+		{
+			input: Segments{
+				Segment{0, 1},
+				Segment{2, 3},
+			},
+			want: nil,
+		},
+	}
+
+	for _, test := range testCases {
+		got := test.input.OverlapEdges()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.OverlapEdges() = %v, want %v", test.input, got, test.want)
+		}
+		// Check against a brute-force search over all pairs.
+		var bruteForce [][2]int
+		for i, s := range test.input {
+			for j := i + 1; j < len(test.input); j++ {
+				if _, ok := SimpleIntersection(s, test.input[j]); ok {
+					bruteForce = append(bruteForce, [2]int{i, j})
+				}
+			}
+		}
+		if !reflect.DeepEqual(got, bruteForce) {
+			t.Errorf("%s.OverlapEdges() = %v, brute force gives %v", test.input, got, bruteForce)
+		}
+	}
+}
+
+func TestClustersWithGap(t *testing.T) {
+	testCases := []struct {
+		input  Segments
+		maxGap int64
+		want   []Segments
+	}{
+		{
+			input: Segments{
+				Segment{12, 15},
+				Segment{0, 10},
+				Segment{30, 40},
+				Segment{2, 3},
+			},
+			maxGap: 5,
+			want: []Segments{
+				{Segment{0, 10}, Segment{2, 3}, Segment{12, 15}},
+				{Segment{30, 40}},
+			},
+		},
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{10, 12},
+				Segment{13, 15},
+			},
+			maxGap: 0,
+			want: []Segments{
+				{Segment{0, 10}, Segment{10, 12}},
+				{Segment{13, 15}},
+			},
+		},
+		{
+			input:  nil,
+			maxGap: 0,
+			want:   nil,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.ClustersWithGap(test.maxGap); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.ClustersWithGap(%d) = %v, want %v", test.input, test.maxGap, got, test.want)
+		}
+	}
+}
+
+func TestDedupByOverlap(t *testing.T) {
+	testCases := []struct {
+		description string
+		input       Segments
+		minIoU      float64
+		want        Segments
+	}{
+		{
+			description: "heavily overlapping detections keep the longer one",
+			input: Segments{
+				Segment{1, 10},
+				Segment{0, 10},
+				Segment{30, 35},
+			},
+			minIoU: 0.5,
+			want: Segments{
+				Segment{0, 10},
+				Segment{30, 35},
+			},
+		},
+		{
+			description: "slightly overlapping detections are both kept",
+			input: Segments{
+				Segment{8, 12},
+				Segment{0, 10},
+			},
+			minIoU: 0.5,
+			want: Segments{
+				Segment{0, 10},
+				Segment{8, 12},
+			},
+		},
+		{
+			description: "empty input",
+			input:       nil,
+			minIoU:      0.5,
+			want:        nil,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := DedupByOverlap(test.input, test.minIoU); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: DedupByOverlap(%s, %.2f) = %s, want %s",
+				test.description, test.input, test.minIoU, got, test.want)
+		}
+	}
+}
+
+func TestSequenceOverlapScore(t *testing.T) {
+	testCases := []struct {
+		description string
+		x, y        Segments
+		want        float64
+	}{
+		{
+			description: "identical sequences",
+			x:           Segments{Segment{0, 10}, Segment{20, 30}, Segment{40, 50}},
+			y:           Segments{Segment{0, 10}, Segment{20, 30}, Segment{40, 50}},
+			want:        3,
+		},
+		{
+			description: "clear best alignment with an extra segment",
+			x:           Segments{Segment{0, 10}, Segment{20, 30}, Segment{40, 50}},
+			y:           Segments{Segment{0, 10}, Segment{12, 14}, Segment{25, 30}, Segment{40, 50}},
+			want:        2.5,
+		},
+		{
+			description: "shuffled sequence",
+			x:           Segments{Segment{0, 10}, Segment{20, 30}, Segment{40, 50}},
+			y:           Segments{Segment{40, 50}, Segment{20, 30}, Segment{0, 10}},
+			want:        1,
+		},
+		{
+			description: "empty sequence",
+			x:           Segments{Segment{0, 10}},
+			y:           nil,
+			want:        0,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := SequenceOverlapScore(test.x, test.y); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: SequenceOverlapScore(%s, %s) = %f, want %f", test.description, test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	testCases := []struct {
+		x, y Segments
+		want float64
+	}{
+		{
+			x:    Segments{Segment{0, 10}},
+			y:    Segments{Segment{5, 15}},
+			want: 5.0 / 15,
+		},
+		{
+			x:    Segments{Segment{0, 10}, Segment{2, 8}},
+			y:    Segments{Segment{0, 10}},
+			want: 1,
+		},
+		{
+			x:    Segments{Segment{0, 2}},
+			y:    Segments{Segment{2, 4}},
+			want: 0,
+		},
+		{
+			x:    nil,
+			y:    Segments{Segment{3, 3}},
+			want: 0,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := Jaccard(test.x, test.y); got != test.want {
+			t.Errorf("Jaccard(%s, %s) = %v, want %v", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestMinimalCover(t *testing.T) {
+	testCases := []struct {
+		description string
+		target      Segment
+		pieces      Segments
+		want        Segments
+		wantOk      bool
+	}{
+		{
+			description: "coverable target",
+			target:      Segment{0, 20},
+			pieces: Segments{
+				Segment{8, 15},
+				Segment{-5, 3},
+				Segment{2, 10},
+				Segment{0, 5},
+				Segment{10, 25},
+				Segment{4, 9},
+			},
+			want: Segments{
+				Segment{0, 5},
+				Segment{2, 10},
+				Segment{10, 25},
+			},
+			wantOk: true,
+		},
+		{
+			description: "gap in the pieces",
+			target:      Segment{0, 20},
+			pieces: Segments{
+				Segment{0, 5},
+				Segment{6, 20},
+			},
+			want:   nil,
+			wantOk: false,
+		},
+		{
+			description: "pieces start after the target",
+			target:      Segment{0, 20},
+			pieces: Segments{
+				Segment{1, 20},
+			},
+			want:   nil,
+			wantOk: false,
+		},
+		{
+			description: "point target",
+			target:      Segment{5, 5},
+			pieces: Segments{
+				Segment{0, 5},
+			},
+			want: Segments{
+				Segment{0, 5},
+			},
+			wantOk: true,
+		},
+	}
+
+	for _, test := range testCases {
+		if got, ok := MinimalCover(test.target, test.pieces); !reflect.DeepEqual(got, test.want) || ok != test.wantOk {
+			t.Errorf("%s: MinimalCover(%s, %s) = %s, %t, want %s, %t",
+				test.description, test.target, test.pieces, got, ok, test.want, test.wantOk)
+		}
+	}
+}
+
+func TestPackWithinWindows(t *testing.T) {
+	testCases := []struct {
+		description string
+		jobs        []int64
+		windows     Segments
+		want        Segments
+		wantOk      bool
+	}{
+		{
+			description: "three jobs fit in two windows",
+			jobs:        []int64{3, 5, 1},
+			windows: Segments{
+				Segment{10, 15},
+				Segment{0, 4},
+			},
+			want: Segments{
+				Segment{0, 3},
+				Segment{10, 15},
+				Segment{3, 4},
+			},
+			wantOk: true,
+		},
+		{
+			description: "jobs do not fit",
+			jobs:        []int64{3, 5, 2},
+			windows: Segments{
+				Segment{0, 4},
+				Segment{10, 14},
+			},
+			want:   nil,
+			wantOk: false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got, ok := PackWithinWindows(test.jobs, test.windows); !reflect.DeepEqual(got, test.want) || ok != test.wantOk {
+			t.Errorf("%s: PackWithinWindows(%v, %s) = %s, %t, want %s, %t",
+				test.description, test.jobs, test.windows, got, ok, test.want, test.wantOk)
+		}
+	}
+}
+
+func TestComplement(t *testing.T) {
+	testCases := []struct {
+		description string
+		ss          Segments
+		superset    Segment
+		want        Segments
+	}{
+		{
+			description: "set of segments is empty",
+			ss:          nil,
+			superset:    Segment{0, 100},
+			want: Segments{
+				Segment{0, 100},
+			},
+		},
+		{
+			description: "segments only contains a point segment",
+			ss: Segments{
+				Segment{2, 2},
+			},
+			superset: Segment{0, 3},
+			want: Segments{
+				Segment{0, 2},
+				Segment{2, 3},
+			},
+		},
+		{
+			description: "superset is empty",
+			ss: Segments{
+				Segment{1, 2},
+				Segment{3, 4},
+			},
+			superset: Segment{},
+			want:     nil,
+		},
+		{
+			description: "superset is a true superset of the segments",
+			ss: Segments{
+				Segment{1, 3},
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+			superset: Segment{-10, 6},
+			want: Segments{
+				Segment{-10, 0},
+				Segment{3, 4},
+			},
+		},
+		{
+			description: "superset.end lies within a segment",
+			ss: Segments{
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+			superset: Segment{-10, 5},
+			want: Segments{
+				Segment{-10, 0},
+				Segment{2, 4},
+			},
+		},
+		{
+			description: "superset.end lies between segments",
+			ss: Segments{
+				Segment{4, 6},
+				Segment{0, 2},
+			},
+			superset: Segment{-10, 3},
+			want: Segments{
+				Segment{-10, 0},
+				Segment{2, 3},
+			},
+		},
+		{
+			description: "superset lies completely below all segments",
+			ss: Segments{
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+			superset: Segment{-10, -1},
+			want: Segments{
+				Segment{-10, -1},
+			},
+		},
+		{
+			description: "superset.start lies within a segment",
+			ss: Segments{
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+			superset: Segment{1, 10},
+			want: Segments{
+				Segment{2, 4},
+				Segment{6, 10},
+			},
+		},
+		{
+			description: "superset.start lies between segments",
+			ss: Segments{
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+			superset: Segment{3, 10},
+			want: Segments{
+				Segment{3, 4},
+				Segment{6, 10},
+			},
+		},
+		{
+			description: "superset lies completely above the segments",
+			ss: Segments{
+				Segment{4, 6},
+				Segment{0, 2},
+			},
+			superset: Segment{8, 10},
+			want: Segments{
+				Segment{8, 10},
+			},
+		},
+		{
+			description: "superset is a sub-segment of RemoveOverlaps(ss)",
+			ss: Segments{
+				Segment{1, 3},
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+			superset: Segment{0, 3},
+			want:     nil,
+		},
+		{
+			description: "real-life session example part 1",
+			ss: Segments{
+				Segment{0, 30000},
+				Segment{36571515, 36901489},
+			},
+			superset: Segment{0, 29347},
+			want:     nil,
+		},
+		{
+			description: "real-life session example part 2",
+			ss: Segments{
+				Segment{0, 30000},
+				Segment{36571515, 36901489},
+			},
+			superset: Segment{36569394, 36596094},
+			want: Segments{
+				Segment{36569394, 36571515},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := Complement(test.superset, test.ss); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Complement(superset = %s, ss = %s) = %s, want %s",
+				test.description, test.superset, test.ss, got, test.want)
+		}
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	testCases := []struct {
+		description string
+		s           Segment
+		ss, want    Segments
+	}{
+		{
+			description: "parts of the window are processed",
+			s:           Segment{0, 10},
+			ss:          Segments{Segment{2, 3}, Segment{8, 12}},
+			want:        Segments{Segment{0, 2}, Segment{3, 8}},
+		},
+		{
+			description: "nothing is processed",
+			s:           Segment{0, 10},
+			ss:          nil,
+			want:        Segments{Segment{0, 10}},
+		},
+		{
+			description: "window is fully covered",
+			s:           Segment{0, 10},
+			ss:          Segments{Segment{0, 4}, Segment{4, 10}},
+			want:        Segments{},
+		},
+		{
+			description: "point segment not covered",
+			s:           Segment{5, 5},
+			ss:          Segments{Segment{0, 4}},
+			want:        Segments{Segment{5, 5}},
+		},
+		{
+			description: "point segment covered",
+			s:           Segment{4, 4},
+			ss:          Segments{Segment{0, 4}},
+			want:        Segments{},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.Subtract(test.ss); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: %s.Subtract(%s) = %#v, want %#v", test.description, test.s, test.ss, got, test.want)
+		}
+	}
+}
+
+func TestPartition(t *testing.T) {
+	testCases := []struct {
+		description   string
+		superset      Segment
+		ss            Segments
+		wantCovered   Segments
+		wantUncovered Segments
+	}{
+		{
+			description:   "set of segments is empty",
+			superset:      Segment{0, 10},
+			ss:            nil,
+			wantCovered:   nil,
+			wantUncovered: Segments{Segment{0, 10}},
+		},
+		{
+			description: "segments inside and across the superset",
+			superset:    Segment{0, 10},
+			ss: Segments{
+				Segment{8, 15},
+				Segment{2, 3},
+				Segment{-5, 1},
+			},
+			wantCovered:   Segments{Segment{0, 1}, Segment{2, 3}, Segment{8, 10}},
+			wantUncovered: Segments{Segment{1, 2}, Segment{3, 8}},
+		},
+		{
+			description:   "superset is covered",
+			superset:      Segment{0, 10},
+			ss:            Segments{Segment{-1, 11}},
+			wantCovered:   Segments{Segment{0, 10}},
+			wantUncovered: nil,
+		},
+		{
+			description:   "point superset is not covered",
+			superset:      Segment{5, 5},
+			ss:            Segments{Segment{0, 2}},
+			wantCovered:   nil,
+			wantUncovered: Segments{Segment{5, 5}},
+		},
+		{
+			description:   "superset is not well-defined",
+			superset:      Segment{5, 0},
+			ss:            Segments{Segment{0, 2}},
+			wantCovered:   nil,
+			wantUncovered: nil,
+		},
+	}
+
+	for _, test := range testCases {
+		covered, uncovered := Partition(test.superset, test.ss)
+		if !reflect.DeepEqual(covered, test.wantCovered) || !reflect.DeepEqual(uncovered, test.wantUncovered) {
+			t.Errorf("%s: Partition(%s, %s) = %s, %s, want %s, %s", test.description, test.superset, test.ss,
+				covered, uncovered, test.wantCovered, test.wantUncovered)
+		}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	superset := Segment{-25, 25}
+	for i := 0; i < 100; i++ {
+		ss := randomSegments(r, 10, 70, 10)
+		covered, uncovered := Partition(superset, ss)
+		if got := Union(covered, uncovered); !reflect.DeepEqual(got, Segments{superset}) {
+			t.Errorf("Union(Partition(%s, %s)) = %s, want %s", superset, ss, got, superset)
+		}
+		if want := Complement(superset, ss); !reflect.DeepEqual(uncovered, want) {
+			t.Errorf("Partition(%s, %s) uncovered = %s, want Complement = %s", superset, ss, uncovered, want)
+		}
+		if want := Intersect(Segments{superset}, ss); !reflect.DeepEqual(covered, want) {
+			t.Errorf("Partition(%s, %s) covered = %s, want Intersect = %s", superset, ss, covered, want)
+		}
+	}
+}
+
+func TestSetDiff(t *testing.T) {
+	testCases := []struct {
+		description string
+		x, y, want  Segments
+	}{
+		{
+			description: "y is empty",
+			x: Segments{
+				Segment{0, 2},
+			},
+			y: Segments{},
+			want: Segments{
+				Segment{0, 2},
+			},
+		},
+		{
+			description: "x is empty",
+			x:           Segments{},
+			y: Segments{
+				Segment{0, 2},
+			},
+			want: nil,
+		},
+		{
+			description: "there is some overlap between x and y",
+			x: Segments{
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+			y: Segments{
+				Segment{1, 3},
+				Segment{3, 5},
+			},
+			want: Segments{
+				Segment{0, 1},
+				Segment{5, 6},
+			},
+		},
+		{
+			description: "there is no overlap between x and y",
+			x: Segments{
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+			y: Segments{
+				Segment{-6, -4},
+				Segment{-2, 0},
+			},
+			want: Segments{
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+		},
+		{
+			description: "there is some overlap within x",
+			x: Segments{
+				Segment{0, 4},
+				Segment{2, 6},
+			},
+			y: Segments{
+				Segment{1, 3},
+				Segment{3, 5},
+			},
+			want: Segments{
+				Segment{0, 1},
+				Segment{5, 6},
+			},
+		},
+		{
+			description: "there is some overlap within y",
+			x: Segments{
+				Segment{0, 2},
+				Segment{4, 5},
+			},
+			y: Segments{
+				Segment{1, 5},
+				Segment{3, 7},
+			},
+			want: Segments{
+				Segment{0, 1},
+			},
+		},
+		{
+			description: "real-life session example",
+			x: Segments{
+				Segment{0, 29347},
+				Segment{36569394, 36596094},
+			},
+			y: Segments{
+				Segment{0, 30000},
+				Segment{36571515, 36901489},
+			},
+			want: Segments{
+				Segment{36569394, 36571515},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := SetDiff(test.x, test.y); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: SetDiff(%s, %s) = %s, want %s",
+				test.description, test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestSymmetricDifference(t *testing.T) {
+	testCases := []struct {
+		description string
+		x, y, want  Segments
+	}{
+		{
+			description: "some overlap between x and y",
+			x: Segments{
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+			y: Segments{
+				Segment{1, 3},
+				Segment{3, 5},
+			},
+			want: Segments{
+				Segment{0, 1},
+				Segment{2, 4},
+				Segment{5, 6},
+			},
+		},
+		{
+			description: "x and y touch at a point",
+			x: Segments{
+				Segment{0, 2},
+			},
+			y: Segments{
+				Segment{2, 4},
+			},
+			want: Segments{
+				Segment{0, 4},
+			},
+		},
+		{
+			description: "x equals y",
+			x: Segments{
+				Segment{0, 2},
+				Segment{1, 4},
+			},
+			y: Segments{
+				Segment{0, 4},
+			},
+			want: nil,
+		},
+		{
+			description: "y is empty",
+			x: Segments{
+				Segment{4, 6},
+				Segment{0, 2},
+			},
+			y: nil,
+			want: Segments{
+				Segment{0, 2},
+				Segment{4, 6},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := SymmetricDifference(test.x, test.y); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: SymmetricDifference(%s, %s) = %s, want %s",
+				test.description, test.x, test.y, got, test.want)
+		}
+	}
+
+	// Check against the definition on random inputs.
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 1000; n++ {
+		x, y := randomSegments(r, 5, 40, 10), randomSegments(r, 5, 40, 10)
+		if got, want := SymmetricDifference(x, y), Union(SetDiff(x, y), SetDiff(y, x)); !reflect.DeepEqual(got, want) {
+			t.Errorf("SymmetricDifference(%s, %s) = %s, but Union(SetDiff(x, y), SetDiff(y, x)) = %s", x, y, got, want)
+		}
+	}
+}
+
+func TestSupersedeLatest(t *testing.T) {
+	testCases := []struct {
+		description string
+		input, want Segments
+	}{
+		{
+			description: "later segment trims the tail of an earlier one",
+			input: Segments{
+				Segment{0, 10},
+				Segment{7, 15},
+			},
+			want: Segments{
+				Segment{0, 7},
+				Segment{7, 15},
+			},
+		},
+		{
+			description: "later segment splits an earlier one",
+			input: Segments{
+				Segment{0, 10},
+				Segment{20, 30},
+				Segment{3, 5},
+			},
+			want: Segments{
+				Segment{0, 3},
+				Segment{3, 5},
+				Segment{5, 10},
+				Segment{20, 30},
+			},
+		},
+		{
+			description: "later segment hides an earlier one",
+			input: Segments{
+				Segment{3, 5},
+				Segment{8, 8},
+				Segment{0, 10},
+			},
+			want: Segments{
+				Segment{0, 10},
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := SupersedeLatest(test.input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: SupersedeLatest(%s) = %s, want %s", test.description, test.input, got, test.want)
+		}
+	}
+}
+
+func TestDiffSummary(t *testing.T) {
+	testCases := []struct {
+		description                           string
+		before, after                         Segments
+		wantAdded, wantRemoved, wantUnchanged int64
+	}{
+		{
+			description: "real-life session example",
+			before: Segments{
+				Segment{0, 29347},
+				Segment{36569394, 36596094},
+			},
+			after: Segments{
+				Segment{0, 30000},
+				Segment{36571515, 36901489},
+			},
+			wantAdded:     (30000 - 29347) + (36901489 - 36596094),
+			wantRemoved:   36571515 - 36569394,
+			wantUnchanged: 29347 + (36596094 - 36571515),
+		},
+		{
+			description: "nothing changed",
+			before: Segments{
+				Segment{0, 5},
+				Segment{3, 10},
+			},
+			after: Segments{
+				Segment{0, 10},
+			},
+			wantAdded:     0,
+			wantRemoved:   0,
+			wantUnchanged: 10,
+		},
+		{
+			description: "before is empty",
+			before:      nil,
+			after: Segments{
+				Segment{0, 10},
+			},
+			wantAdded:     10,
+			wantRemoved:   0,
+			wantUnchanged: 0,
+		},
+	}
+
+	for _, test := range testCases {
+		added, removed, unchanged := DiffSummary(test.before, test.after)
+		if added != test.wantAdded || removed != test.wantRemoved || unchanged != test.wantUnchanged {
+			t.Errorf("%s: DiffSummary(%s, %s) = %d, %d, %d, want %d, %d, %d",
+				test.description, test.before, test.after, added, removed, unchanged,
+				test.wantAdded, test.wantRemoved, test.wantUnchanged)
+		}
+	}
+}
+
+func TestEqualSetsIgnoringSlivers(t *testing.T) {
+	testCases := []struct {
+		description string
+		x, y        Segments
+		sliverLen   int64
+		want        bool
+	}{
+		{
+			description: "sets differing by a length-1 sliver",
+			x:           Segments{Segment{0, 10}, Segment{20, 30}},
+			y:           Segments{Segment{0, 11}, Segment{20, 30}, Segment{40, 41}},
+			sliverLen:   2,
+			want:        true,
+		},
+		{
+			description: "sets differing by a length-1 sliver, with no threshold",
+			x:           Segments{Segment{0, 10}, Segment{20, 30}},
+			y:           Segments{Segment{0, 11}, Segment{20, 30}, Segment{40, 41}},
+			sliverLen:   0,
+			want:        false,
+		},
+		{
+			description: "sets with a real difference",
+			x:           Segments{Segment{0, 10}},
+			y:           Segments{Segment{0, 20}},
+			sliverLen:   2,
+			want:        false,
+		},
+		{
+			description: "same coverage",
+			x:           Segments{Segment{0, 5}, Segment{5, 10}},
+			y:           Segments{Segment{0, 10}},
+			sliverLen:   0,
+			want:        true,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := EqualSetsIgnoringSlivers(test.x, test.y, test.sliverLen); got != test.want {
+			t.Errorf("%s: EqualSetsIgnoringSlivers(%s, %s, %d) = %t, want %t",
+				test.description, test.x, test.y, test.sliverLen, got, test.want)
+		}
+	}
+}
+
+func TestThreeWayMerge(t *testing.T) {
+	testCases := []struct {
+		description   string
+		base, a, b    Segments
+		wantMerged    Segments
+		wantConflicts Segments
+	}{
+		{
+			description:   "region added by a, removed by b",
+			base:          Segments{Segment{0, 10}},
+			a:             Segments{Segment{0, 15}},
+			b:             Segments{Segment{0, 8}},
+			wantMerged:    Segments{Segment{0, 8}, Segment{10, 15}},
+			wantConflicts: nil,
+		},
+		{
+			description:   "region added by a touching a region removed by b",
+			base:          Segments{Segment{0, 10}},
+			a:             Segments{Segment{0, 15}},
+			b:             Segments{Segment{0, 5}},
+			wantMerged:    Segments{Segment{0, 5}, Segment{10, 15}},
+			wantConflicts: nil,
+		},
+		{
+			description:   "different removals over the same region",
+			base:          Segments{Segment{0, 10}},
+			a:             Segments{Segment{0, 3}},
+			b:             Segments{Segment{0, 6}},
+			wantMerged:    Segments{Segment{0, 10}},
+			wantConflicts: Segments{Segment{3, 10}},
+		},
+		{
+			description:   "region added by both",
+			base:          Segments{Segment{0, 10}},
+			a:             Segments{Segment{0, 10}, Segment{20, 30}},
+			b:             Segments{Segment{20, 30}, Segment{0, 10}},
+			wantMerged:    Segments{Segment{0, 10}, Segment{20, 30}},
+			wantConflicts: nil,
+		},
+		{
+			description:   "separate changes on each side",
+			base:          Segments{Segment{0, 10}, Segment{20, 30}},
+			a:             Segments{Segment{0, 5}, Segment{20, 30}},
+			b:             Segments{Segment{0, 10}, Segment{20, 40}},
+			wantMerged:    Segments{Segment{0, 5}, Segment{20, 40}},
+			wantConflicts: nil,
+		},
+		{
+			description:   "different additions over the same region",
+			base:          nil,
+			a:             Segments{Segment{20, 30}},
+			b:             Segments{Segment{25, 35}},
+			wantMerged:    nil,
+			wantConflicts: Segments{Segment{20, 35}},
+		},
+	}
+
+	for _, test := range testCases {
+		merged, conflicts := ThreeWayMerge(test.base, test.a, test.b)
+		if !reflect.DeepEqual(merged, test.wantMerged) || !reflect.DeepEqual(conflicts, test.wantConflicts) {
+			t.Errorf("%s: ThreeWayMerge(%s, %s, %s) = %s, %s, want %s, %s",
+				test.description, test.base, test.a, test.b, merged, conflicts, test.wantMerged, test.wantConflicts)
+		}
+	}
+}
+
+func TestGaps(t *testing.T) {
+	testCases := []struct {
+		input, want Segments
+	}{
+		{
+			input: Segments{
+				Segment{10, 12},
+				Segment{0, 2},
+				Segment{5, 7},
+			},
+			want: Segments{
+				Segment{2, 5},
+				Segment{7, 10},
+			},
+		},
+		{
+			input: Segments{
+				Segment{0, 5},
+				Segment{5, 7},
+				Segment{3, 4},
+			},
+			want: nil,
+		},
+		{
+			input: Segments{
+				Segment{0, 5},
+			},
+			want: nil,
+		},
+		{
+			input: nil,
+			want:  nil,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := Gaps(test.input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Gaps(%s) = %s, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestInvert(t *testing.T) {
+	testCases := []struct {
+		description string
+		input, want Segments
+	}{
+		{
+			description: "empty input",
+			input:       nil,
+			want:        Segments{Segment{math.MinInt64, math.MaxInt64}},
+		},
+		{
+			description: "gaps and tails",
+			input:       Segments{Segment{10, 20}, Segment{0, 5}, Segment{3, 4}},
+			want: Segments{
+				Segment{math.MinInt64, 0},
+				Segment{5, 10},
+				Segment{20, math.MaxInt64},
+			},
+		},
+		{
+			description: "input reaches both ends of the range",
+			input:       Segments{Segment{math.MinInt64, 3}, Segment{8, math.MaxInt64}},
+			want:        Segments{Segment{3, 8}},
+		},
+		{
+			description: "input is the whole range",
+			input:       Segments{Segment{math.MinInt64, math.MaxInt64}},
+			want:        nil,
+		},
+	}
+
+	for _, test := range testCases {
+		got := Invert(test.input)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Invert(%s) = %s, want %s", test.description, test.input, got, test.want)
+		}
+		// Inverting again gives the input back, since it has no point segments.
+		if back := Invert(got); !back.Equal(RemoveOverlaps(test.input)) {
+			t.Errorf("%s: Invert(Invert(%s)) = %s, want %s", test.description, test.input, back, RemoveOverlaps(test.input))
+		}
+	}
+}
+
+func TestLargestAndSmallestGap(t *testing.T) {
+	testCases := []struct {
+		input                     Segments
+		wantLargest, wantSmallest Segment
+		wantOk                    bool
+	}{
+		{
+			input: Segments{
+				Segment{6, 8},
+				Segment{0, 2},
+				Segment{1, 3},
+				Segment{15, 16},
+			},
+			wantLargest:  Segment{8, 15},
+			wantSmallest: Segment{3, 6},
+			wantOk:       true,
+		},
+		{
+			input: Segments{
+				Segment{0, 2},
+				Segment{5, 7},
+				Segment{10, 12},
+			},
+			wantLargest:  Segment{2, 5},
+			wantSmallest: Segment{2, 5},
+			wantOk:       true,
+		},
+		{
+			input: Segments{
+				Segment{0, 2},
+				Segment{2, 5},
+			},
+			wantOk: false,
+		},
+		{
+			input:  nil,
+			wantOk: false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got, ok := test.input.LargestGap(); ok != test.wantOk || got != test.wantLargest {
+			t.Errorf("%s.LargestGap() = %s, %t, want %s, %t", test.input, got, ok, test.wantLargest, test.wantOk)
+		}
+		if got, ok := test.input.SmallestGap(); ok != test.wantOk || got != test.wantSmallest {
+			t.Errorf("%s.SmallestGap() = %s, %t, want %s, %t", test.input, got, ok, test.wantSmallest, test.wantOk)
+		}
+	}
+}
+
+func TestIsIntersectionEmpty(t *testing.T) {
+	testCases := []struct {
+		s    Segment
+		tt   Segments
+		want bool
+	}{
+		{
+			s: Segment{11, 13},
+			tt: Segments{
+				Segment{12, 14},
+				Segment{20, 25},
+			},
+			want: false,
+		},
+		{
+			s: Segment{11, 13},
+			tt: Segments{
+				Segment{120, 140},
+				Segment{20, 25},
+			},
+			want: true,
+		},
+		{
+			s:    Segment{11, 13},
+			tt:   Segments{},
+			want: true,
+		},
+		{
+			s: Segment{1, 1},
+			tt: Segments{
+				Segment{0, 2},
+			},
+			want: false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.IsIntersectionEmpty(test.tt); got != test.want {
+			t.Errorf("%s.IsIntersectionEmpty(%s) = %t, should be %t", test.s, test.tt, got, test.want)
+		}
+	}
+}
+
+func TestIsIntersectionEmptyMatchesIntersect(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 1000; n++ {
+		s := randomSegment(r, 100, 20)
+		tt := randomSegments(r, 5, 100, 20)
+		if got, want := s.IsIntersectionEmpty(tt), len(Intersect(Segments{s}, tt)) == 0; got != want {
+			t.Errorf("%s.IsIntersectionEmpty(%s) = %t, but Intersect gives %t", s, tt, got, want)
+		}
+	}
+
+	minimal := Segment{math.MinInt64, 3}
+	if got, want := minimal.IsIntersectionEmpty(Segments{Segment{0, 1}}), false; got != want {
+		t.Errorf("%s.IsIntersectionEmpty(%s) = %t, want %t", minimal, Segments{Segment{0, 1}}, got, want)
+	}
+	if got, want := (Segment{0, 1}).IsIntersectionEmpty(Segments{minimal}), false; got != want {
+		t.Errorf("%s.IsIntersectionEmpty(%s) = %t, want %t", Segment{0, 1}, Segments{minimal}, got, want)
+	}
+}
+
+func BenchmarkIsIntersectionEmpty(b *testing.B) {
+	var tt Segments
+	for i := int64(0); i < 1000; i++ {
+		tt = append(tt, Segment{10 * i, 10*i + 5})
+	}
+	s := Segment{5006, 5008}
+
+	b.Run("Intersect", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = len(Intersect(Segments{s}, tt)) == 0
+		}
+	})
+	b.Run("IsIntersectionEmpty", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			s.IsIntersectionEmpty(tt)
+		}
+	})
+}
+
+func TestIntersectWith(t *testing.T) {
+	testCases := []struct {
+		s        Segment
+		ss, want Segments
+	}{
+		{
+			s:    Segment{0, 10},
+			ss:   Segments{Segment{8, 15}, Segment{-5, 2}, Segment{4, 5}},
+			want: Segments{Segment{0, 2}, Segment{4, 5}, Segment{8, 10}},
+		},
+		{
+			s:    Segment{0, 10},
+			ss:   Segments{Segment{10, 12}},
+			want: Segments{Segment{10, 10}},
+		},
+		{
+			s:    Segment{0, 10},
+			ss:   Segments{Segment{11, 12}},
+			want: nil,
+		},
+	}
+
+	for _, test := range testCases {
+		got := test.s.IntersectWith(test.ss)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.IntersectWith(%s) = %s, want %s", test.s, test.ss, got, test.want)
+		}
+		if empty := test.s.IsIntersectionEmpty(test.ss); empty != (len(got) == 0) {
+			t.Errorf("%s.IsIntersectionEmpty(%s) = %t, but IntersectWith gives %s", test.s, test.ss, empty, got)
+		}
+	}
+}
+
+func TestContainmentForest(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  map[int][]int
+	}{
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{2, 8},
+				Segment{3, 4},
+			},
+			want: map[int][]int{0: {1}, 1: {2}},
+		},
+		{
+			input: Segments{
+				Segment{3, 4},
+				Segment{20, 30},
+				Segment{0, 10},
+				Segment{6, 9},
+				Segment{21, 22},
+			},
+			want: map[int][]int{1: {4}, 2: {0, 3}},
+		},
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{0, 10},
+			},
+			want: map[int][]int{0: {1}},
+		},
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{0, 10},
+				Segment{0, 10},
+			},
+			want: map[int][]int{0: {1}, 1: {2}},
+		},
+		{
+			input: Segments{
+				Segment{6, 8},
+				Segment{5, 15},
+				Segment{0, 10},
+			},
+			want: map[int][]int{1: {0}},
+		},
+		{
+			input: Segments{
+				Segment{0, 1},
+				Segment{2, 3},
+			},
+			want: map[int][]int{},
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.input.ContainmentForest(); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.ContainmentForest() = %v, want %v", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSegmentOverlapsAtLeast(t *testing.T) {
+	testCases := []struct {
+		s, t   Segment
+		minLen int64
+		want   bool
+	}{
+		{
+			s:      Segment{0, 5},
+			t:      Segment{2, 10},
+			minLen: 2,
+			want:   true,
+		},
+		{
+			s:      Segment{0, 5},
+			t:      Segment{2, 10},
+			minLen: 5,
+			want:   false,
+		},
+		{
+			s:      Segment{0, 5},
+			t:      Segment{5, 10},
+			minLen: 1,
+			want:   false,
+		},
+		{
+			s:      Segment{0, 5},
+			t:      Segment{5, 10},
+			minLen: 0,
+			want:   true,
+		},
+		{
+			s:      Segment{0, 5},
+			t:      Segment{6, 10},
+			minLen: 0,
+			want:   false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.OverlapsAtLeast(test.t, test.minLen); got != test.want {
+			t.Errorf("%s.OverlapsAtLeast(%s, %d) = %t, want %t", test.s, test.t, test.minLen, got, test.want)
+		}
+	}
+}
+
+func TestSegmentsOverlapsAtLeast(t *testing.T) {
+	ss := Segments{
+		Segment{0, 5},
+		Segment{4, 6},
+		Segment{7, 20},
+		Segment{30, 40},
+	}
+	target := Segment{2, 10}
+	if got, want := ss.OverlapsAtLeast(target, 2), []int{0, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.OverlapsAtLeast(%s, 2) = %v, want %v", ss, target, got, want)
+	}
+	if got, want := ss.OverlapsAtLeast(target, 3), []int{0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.OverlapsAtLeast(%s, 3) = %v, want %v", ss, target, got, want)
+	}
+}
+
+func TestIsSubSegment(t *testing.T) {
+	testCases := []struct {
+		s, t Segment
+		want bool
+	}{
+		{
+			s:    Segment{2, 30},
+			t:    Segment{20, 40},
+			want: false,
+		},
+		{
+			s:    Segment{4, 6},
+			t:    Segment{3, 10},
+			want: true,
+		},
+		{
+			s:    Segment{-10, 60},
+			t:    Segment{3, 10},
+			want: false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.IsSubSegment(test.t); got != test.want {
+			t.Errorf("%s.IsSubSegment(%s) is %t, expected is %t", test.s, test.t, got, test.want)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	testCases := []struct {
+		s, t Segment
+		want bool
+	}{
+		{
+			s:    Segment{3, 10},
+			t:    Segment{4, 6},
+			want: true,
+		},
+		{
+			s:    Segment{20, 40},
+			t:    Segment{2, 30},
+			want: false,
+		},
+		{
+			s:    Segment{3, 10},
+			t:    Segment{3, 10},
+			want: true,
+		},
+		{
+			s:    Segment{3, 10},
+			t:    Segment{10, 10},
+			want: true,
+		},
+		{
+			s:    Segment{3, 10},
+			t:    Segment{11, 11},
+			want: false,
+		},
+		{
+			s:    Segment{5, 5},
+			t:    Segment{5, 5},
+			want: true,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.Contains(test.t); got != test.want {
+			t.Errorf("%s.Contains(%s) is %t, expected is %t", test.s, test.t, got, test.want)
+		}
+	}
+}
+
+func TestSegmentEqual(t *testing.T) {
+	testCases := []struct {
+		s, t Segment
+		want bool
+	}{
+		{
+			s:    Segment{3, 10},
+			t:    Segment{3, 10},
+			want: true,
+		},
+		{
+			s:    Segment{3, 10},
+			t:    Segment{3, 11},
+			want: false,
+		},
+		{
+			s:    Segment{3, 10},
+			t:    Segment{2, 10},
+			want: false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.Equal(test.t); got != test.want {
+			t.Errorf("%s.Equal(%s) is %t, expected is %t", test.s, test.t, got, test.want)
+		}
+	}
+}
+
+func TestSegmentsEqual(t *testing.T) {
+	testCases := []struct {
+		ss, tt Segments
+		want   bool
+	}{
+		{
+			ss:   Segments{Segment{0, 1}, Segment{3, 10}},
+			tt:   Segments{Segment{0, 1}, Segment{3, 10}},
+			want: true,
+		},
+		{
+			ss:   Segments{Segment{0, 1}, Segment{3, 10}},
+			tt:   Segments{Segment{3, 10}, Segment{0, 1}},
+			want: false,
+		},
+		{
+			ss:   Segments{Segment{0, 1}},
+			tt:   Segments{Segment{0, 1}, Segment{3, 10}},
+			want: false,
+		},
+		{
+			ss:   nil,
+			tt:   Segments{},
+			want: true,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.ss.Equal(test.tt); got != test.want {
+			t.Errorf("%s.Equal(%s) is %t, expected is %t", test.ss, test.tt, got, test.want)
+		}
+	}
+}
+
+func TestSegmentEqualWithin(t *testing.T) {
+	testCases := []struct {
+		s, t Segment
+		tol  int64
+		want bool
+	}{
+		{s: Segment{3, 10}, t: Segment{3, 10}, tol: 0, want: true},
+		{s: Segment{3, 10}, t: Segment{2, 11}, tol: 1, want: true},
+		{s: Segment{3, 10}, t: Segment{4, 12}, tol: 1, want: false},
+		{s: Segment{3, 10}, t: Segment{5, 10}, tol: 1, want: false},
+		{s: Segment{3, 10}, t: Segment{3, 10}, tol: -1, want: false},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.EqualWithin(test.t, test.tol); got != test.want {
+			t.Errorf("%s.EqualWithin(%s, %d) is %t, expected is %t", test.s, test.t, test.tol, got, test.want)
+		}
+	}
+}
+
+func TestSegmentsEqualWithin(t *testing.T) {
+	ss := Segments{Segment{0, 3}, Segment{7, 10}}
+	// Scaling by 1.5 rounds half away from zero, giving {0, 5}, {11, 15}.
+	transformed := ss.Clone()
+	transformed.LinearTransform(1.5, 0)
+	want := Segments{Segment{0, 4}, Segment{10, 15}}
+	if !transformed.EqualWithin(want, 1) {
+		t.Errorf("%s.EqualWithin(%s, 1) is false, expected is true", transformed, want)
+	}
+	if transformed.EqualWithin(want, 0) {
+		t.Errorf("%s.EqualWithin(%s, 0) is true, expected is false", transformed, want)
+	}
+	if transformed.EqualWithin(want[:1], 1) {
+		t.Errorf("%s.EqualWithin(%s, 1) is true, expected is false", transformed, want[:1])
+	}
+	if !Segments(nil).EqualWithin(Segments{}, 0) {
+		t.Errorf("Segments(nil).EqualWithin(Segments{}, 0) is false, expected is true")
+	}
+}
+
+func TestIsPointOnBoundary(t *testing.T) {
+	testCases := []struct {
+		s    Segment
+		p    int64
+		want bool
+	}{
+		{
+			s:    Segment{2, 30},
+			p:    2,
+			want: true,
+		},
+		{
+			s:    Segment{2, 30},
+			p:    30,
+			want: true,
+		},
+		{
+			s:    Segment{2, 30},
+			p:    11,
+			want: false,
+		},
+		{
+			s:    Segment{2, 30},
+			p:    41,
+			want: false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := test.s.IsPointOnBoundary(test.p); got != test.want {
+			t.Errorf("%s.IsPointOnBoundary(%d) is %t, expected is %t", test.s, test.p, got, test.want)
+		}
+	}
+}
+
+func TestTightenTo(t *testing.T) {
+	testCases := []struct {
+		s      Segment
+		points []int64
+		want   Segment
+		wantOk bool
+	}{
+		{
+			s:      Segment{0, 100},
+			points: []int64{50, -5, 20, 150, 70},
+			want:   Segment{20, 70},
+			wantOk: true,
+		},
+		{
+			s:      Segment{0, 100},
+			points: []int64{100},
+			want:   Segment{100, 100},
+			wantOk: true,
+		},
+		{
+			s:      Segment{0, 100},
+			points: []int64{-5, 150},
+			wantOk: false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got, ok := test.s.TightenTo(test.points); ok != test.wantOk || got != test.want {
+			t.Errorf("%s.TightenTo(%v) = %s, %t, want %s, %t", test.s, test.points, got, ok, test.want, test.wantOk)
+		}
+	}
+}
+
+func TestIsPointInSegment(t *testing.T) {
+	testCases := []struct {
+		s    Segment
+		p    int64
+		want bool
+	}{
+		{
+			s:    Segment{2, 30},
+			p:    11,
+			want: true},
+		{
+			s:    Segment{-10, 30},
+			p:    41,
+			want: false},
+	}
+
+	for _, test := range testCases {
+		if got := IsPointInSegment(test.p, test.s); got != test.want {
+			t.Errorf("IsPointInSegment(%d, %s) is %t, expected is %t", test.p, test.s, got, test.want)
+		}
+	}
+}
+
+func TestIsPointInSegments(t *testing.T) {
+	testCases := []struct {
+		ss   Segments
+		p    int64
+		want bool
+	}{
+		{
+			ss: Segments{
+				Segment{2, 3},
+				Segment{1, 2},
+				Segment{4, 15}},
+			p:    11,
+			want: true},
+		{
+			ss: Segments{
+				Segment{2, 3},
+				Segment{1, 2},
+				Segment{4, 5}},
+			p:    41,
+			want: false,
+		},
+	}
+
+	for _, test := range testCases {
+		if got := IsPointInSegments(test.p, test.ss); got != test.want {
+			t.Errorf("IsPointInSegments(%s, %d) = %t, want %t", test.ss, test.p, got, test.want)
+		}
+	}
+}
+
+func TestFindSegment(t *testing.T) {
+	ss := Segments{
+		Segment{0, 2},
+		Segment{5, 5},
+		Segment{8, 12},
+	}
+	testCases := []struct {
+		p         int64
+		wantIndex int
+		wantOk    bool
+	}{
+		{p: -1, wantIndex: -1, wantOk: false},
+		{p: 0, wantIndex: 0, wantOk: true},
+		{p: 2, wantIndex: 0, wantOk: true},
+		{p: 3, wantIndex: -1, wantOk: false},
+		{p: 5, wantIndex: 1, wantOk: true},
+		{p: 8, wantIndex: 2, wantOk: true},
+		{p: 10, wantIndex: 2, wantOk: true},
+		{p: 12, wantIndex: 2, wantOk: true},
+		{p: 13, wantIndex: -1, wantOk: false},
+	}
+
+	for _, test := range testCases {
+		if i, ok := ss.FindSegment(test.p); i != test.wantIndex || ok != test.wantOk {
+			t.Errorf("%s.FindSegment(%d) = %d, %t, want %d, %t", ss, test.p, i, ok, test.wantIndex, test.wantOk)
+		}
+		if got := ss.ContainsPointSorted(test.p); got != test.wantOk {
+			t.Errorf("%s.ContainsPointSorted(%d) = %t, want %t", ss, test.p, got, test.wantOk)
+		}
+		if got := IsPointInSegments(test.p, ss); got != test.wantOk {
+			t.Errorf("IsPointInSegments(%d, %s) = %t, want %t", test.p, ss, got, test.wantOk)
+		}
+	}
+
+	if i, ok := (Segments{}).FindSegment(0); i != -1 || ok {
+		t.Errorf("Segments{}.FindSegment(0) = %d, %t, want -1, false", i, ok)
+	}
+}
+
+func TestContainsPoints(t *testing.T) {
+	ss := Segments{
+		Segment{8, 12},
+		Segment{0, 2},
+		Segment{1, 5},
+	}
+	ps := []int64{10, -1, 5, 6, 0, 12, 13, 5}
+	want := []bool{true, false, true, false, true, true, false, true}
+	if got := ss.ContainsPoints(ps); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.ContainsPoints(%v) = %v, want %v", ss, ps, got, want)
+	}
+	if want := []int64{10, -1, 5, 6, 0, 12, 13, 5}; !reflect.DeepEqual(ps, want) {
+		t.Errorf("ContainsPoints modified its points to %v", ps)
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		ss := randomSegments(r, 10, 50, 10)
+		ps := make([]int64, r.Intn(20))
+		for j := range ps {
+			ps[j] = r.Int63n(70) - 35
+		}
+		got := ss.ContainsPoints(ps)
+		for j, p := range ps {
+			if want := IsPointInSegments(p, ss); got[j] != want {
+				t.Errorf("%s.ContainsPoints(%v)[%d] = %t, but IsPointInSegments(%d) is %t", ss, ps, j, got[j], p, want)
+			}
+		}
+	}
+}
+
+func TestNearest(t *testing.T) {
+	ss := Segments{
+		Segment{8, 12},
+		Segment{0, 2},
+		Segment{5, 5},
+	}
+	testCases := []struct {
+		p            int64
+		want         Segment
+		wantDistance int64
+	}{
+		{p: -3, want: Segment{0, 2}, wantDistance: 3},
+		{p: 1, want: Segment{0, 2}, wantDistance: 0},
+		{p: 3, want: Segment{0, 2}, wantDistance: 1},
+		{p: 4, want: Segment{5, 5}, wantDistance: 1},
+		{p: 5, want: Segment{5, 5}, wantDistance: 0},
+		{p: 7, want: Segment{8, 12}, wantDistance: 1},
+		{p: 20, want: Segment{8, 12}, wantDistance: 8},
+	}
+
+	for _, test := range testCases {
+		if got, d, ok := ss.Nearest(test.p); got != test.want || d != test.wantDistance || !ok {
+			t.Errorf("%s.Nearest(%d) = %s, %d, %t, want %s, %d, true", ss, test.p, got, d, ok, test.want, test.wantDistance)
+		}
+	}
+
+	if got, d, ok := (Segments{}).Nearest(0); got != (Segment{}) || d != 0 || ok {
+		t.Errorf("Segments{}.Nearest(0) = %s, %d, %t, want %s, 0, false", got, d, ok, Segment{})
+	}
+}
+
+func TestCoverPoint(t *testing.T) {
+	ss := Segments{
+		Segment{10, 12},
+		Segment{0, 4},
+		Segment{6, 8},
+	}
+	testCases := []struct {
+		p    int64
+		want Segments
+	}{
+		{p: 2, want: ss},
+		{p: 5, want: Segments{Segment{0, 5}, Segment{6, 8}, Segment{10, 12}}},
+		{p: 9, want: Segments{Segment{0, 4}, Segment{6, 8}, Segment{9, 12}}},
+		{p: 13, want: Segments{Segment{0, 4}, Segment{6, 8}, Segment{10, 13}}},
+		{p: 20, want: Segments{Segment{0, 4}, Segment{6, 8}, Segment{10, 12}, Segment{20, 20}}},
+	}
+
+	for _, test := range testCases {
+		if got := ss.CoverPoint(test.p); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.CoverPoint(%d) = %s, want %s", ss, test.p, got, test.want)
+		}
+	}
+	if want := (Segments{Segment{10, 12}, Segment{0, 4}, Segment{6, 8}}); !reflect.DeepEqual(ss, want) {
+		t.Errorf("CoverPoint modified its input to %s", ss)
+	}
+
+	edge := Segments{Segment{math.MaxInt64, math.MaxInt64}}
+	if got, want := edge.CoverPoint(math.MinInt64), (Segments{Segment{math.MinInt64, math.MinInt64}, edge[0]}); !reflect.DeepEqual(got, want) {
+		t.Errorf("%s.CoverPoint(%d) = %s, want %s", edge, int64(math.MinInt64), got, want)
+	}
+}
+
+func TestCoverageRLE(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  []DepthRun
+	}{
+		{
+			input: Segments{
+				Segment{0, 10},
+				Segment{2, 8},
+				Segment{5, 15},
+				Segment{20, 25},
+				Segment{25, 30},
+				Segment{7, 7},
 			},
-			superset: Segment{1, 10},
-			want: Segments{
-				Segment{2, 4},
-				Segment{6, 10},
+			want: []DepthRun{
+				{Segment{0, 2}, 1},
+				{Segment{2, 5}, 2},
+				{Segment{5, 8}, 3},
+				{Segment{8, 10}, 2},
+				{Segment{10, 15}, 1},
+				{Segment{15, 20}, 0},
+				{Segment{20, 30}, 1},
 			},
 		},
 		{
-			description: "superset.start lies between segments",
-			ss: Segments{
-				Segment{0, 2},
-				Segment{4, 6},
-			},
-			superset: Segment{3, 10},
-			want: Segments{
-				Segment{3, 4},
-				Segment{6, 10},
-			},
+			input: nil,
+			want:  nil,
 		},
+	}
+
+	for _, test := range testCases {
+		got := test.input.CoverageRLE()
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s.CoverageRLE() = %v, want %v", test.input, got, test.want)
+		}
+		// The runs should cover the span of the input with no holes.
+		for i := 1; i < len(got); i++ {
+			if got[i-1].Seg.end != got[i].Seg.start {
+				t.Errorf("%s.CoverageRLE() has a hole between %s and %s", test.input, got[i-1].Seg, got[i].Seg)
+			}
+		}
+	}
+}
+
+func TestCoverageProfile(t *testing.T) {
+	testCases := []struct {
+		input Segments
+		want  []DepthRun
+	}{
 		{
-			description: "superset lies completely above the segments",
-			ss: Segments{
-				Segment{4, 6},
-				Segment{0, 2},
+			input: Segments{
+				Segment{0, 10},
+				Segment{2, 8},
+				Segment{5, 15},
 			},
-			superset: Segment{8, 10},
-			want: Segments{
-				Segment{8, 10},
+			want: []DepthRun{
+				{Segment{0, 2}, 1},
+				{Segment{2, 5}, 2},
+				{Segment{5, 8}, 3},
+				{Segment{8, 10}, 2},
+				{Segment{10, 15}, 1},
 			},
 		},
 		{
-			description: "superset is a sub-segment of RemoveOverlaps(ss)",
-			ss: Segments{
-				Segment{1, 3},
+			input: Segments{
+				Segment{6, 8},
 				Segment{0, 2},
-				Segment{4, 6},
+				Segment{4, 4},
 			},
-			superset: Segment{0, 3},
-			want:     nil,
-		},
-		{
-			description: "real-life session example part 1",
-			ss: Segments{
-				Segment{0, 30000},
-				Segment{36571515, 36901489},
+			want: []DepthRun{
+				{Segment{0, 2}, 1},
+				{Segment{6, 8}, 1},
 			},
-			superset: Segment{0, 29347},
-			want:     nil,
 		},
 		{
-			description: "real-life session example part 2",
-			ss: Segments{
-				Segment{0, 30000},
-				Segment{36571515, 36901489},
-			},
-			superset: Segment{36569394, 36596094},
-			want: Segments{
-				Segment{36569394, 36571515},
-			},
+			input: nil,
+			want:  nil,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := Complement(test.superset, test.ss); !reflect.DeepEqual(got, test.want) {
-			t.Errorf("%s: Complement(superset = %s, ss = %s) = %s, want %s",
-				test.description, test.superset, test.ss, got, test.want)
+		if got := CoverageProfile(test.input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("CoverageProfile(%s) = %v, want %v", test.input, got, test.want)
 		}
 	}
 }
 
-func TestSetDiff(t *testing.T) {
+func TestMaxOverlap(t *testing.T) {
 	testCases := []struct {
 		description string
-		x, y, want  Segments
+		input       Segments
+		wantDepth   int
+		wantWhere   Segment
 	}{
 		{
-			description: "y is empty",
-			x: Segments{
-				Segment{0, 2},
-			},
-			y: Segments{},
-			want: Segments{
-				Segment{0, 2},
-			},
-		},
-		{
-			description: "x is empty",
-			x:           Segments{},
-			y: Segments{
-				Segment{0, 2},
-			},
-			want: nil,
-		},
-		{
-			description: "there is some overlap between x and y",
-			x: Segments{
-				Segment{0, 2},
-				Segment{4, 6},
-			},
-			y: Segments{
-				Segment{1, 3},
-				Segment{3, 5},
-			},
-			want: Segments{
-				Segment{0, 1},
-				Segment{5, 6},
+			description: "nested overlaps",
+			input: Segments{
+				Segment{0, 10},
+				Segment{2, 8},
+				Segment{5, 15},
 			},
+			wantDepth: 3,
+			wantWhere: Segment{5, 8},
 		},
 		{
-			description: "there is no overlap between x and y",
-			x: Segments{
-				Segment{0, 2},
-				Segment{4, 6},
-			},
-			y: Segments{
-				Segment{-6, -4},
-				Segment{-2, 0},
-			},
-			want: Segments{
-				Segment{0, 2},
-				Segment{4, 6},
+			description: "touching segments overlap at their shared endpoint",
+			input: Segments{
+				Segment{5, 10},
+				Segment{0, 5},
 			},
+			wantDepth: 2,
+			wantWhere: Segment{5, 5},
 		},
 		{
-			description: "there is some overlap within x",
-			x: Segments{
-				Segment{0, 4},
-				Segment{2, 6},
-			},
-			y: Segments{
-				Segment{1, 3},
-				Segment{3, 5},
-			},
-			want: Segments{
-				Segment{0, 1},
-				Segment{5, 6},
+			description: "point segments count at their coordinate",
+			input: Segments{
+				Segment{0, 10},
+				Segment{3, 3},
+				Segment{3, 3},
 			},
+			wantDepth: 3,
+			wantWhere: Segment{3, 3},
 		},
 		{
-			description: "there is some overlap within y",
-			x: Segments{
-				Segment{0, 2},
-				Segment{4, 5},
-			},
-			y: Segments{
-				Segment{1, 5},
-				Segment{3, 7},
-			},
-			want: Segments{
+			description: "first peak is returned",
+			input: Segments{
+				Segment{20, 30},
 				Segment{0, 1},
 			},
+			wantDepth: 1,
+			wantWhere: Segment{0, 1},
 		},
 		{
-			description: "real-life session example",
-			x: Segments{
-				Segment{0, 29347},
-				Segment{36569394, 36596094},
-			},
-			y: Segments{
-				Segment{0, 30000},
-				Segment{36571515, 36901489},
-			},
-			want: Segments{
-				Segment{36569394, 36571515},
-			},
+			description: "empty input",
+			input:       nil,
+			wantDepth:   0,
+			wantWhere:   Segment{},
 		},
 	}
 
 	for _, test := range testCases {
-		if got := SetDiff(test.x, test.y); !reflect.DeepEqual(got, test.want) {
-			t.Errorf("%s: SetDiff(%s, %s) = %s, want %s",
-				test.description, test.x, test.y, got, test.want)
+		if depth, where := MaxOverlap(test.input); depth != test.wantDepth || where != test.wantWhere {
+			t.Errorf("%s: MaxOverlap(%s) = %d, %s, want %d, %s",
+				test.description, test.input, depth, where, test.wantDepth, test.wantWhere)
 		}
 	}
 }
 
-func TestIsIntersectionEmpty(t *testing.T) {
+func TestWeightedCoverage(t *testing.T) {
 	testCases := []struct {
-		s    Segment
-		tt   Segments
-		want bool
+		description string
+		input       Segments
+		weight      func(Segment) float64
+		want        []WeightedRun
 	}{
 		{
-			s: Segment{11, 13},
-			tt: Segments{
-				Segment{12, 14},
-				Segment{20, 25},
+			description: "two overlapping segments of weight 0.5",
+			input: Segments{
+				Segment{0, 10},
+				Segment{5, 15},
+				Segment{20, 30},
 			},
-			want: false,
-		},
-		{
-			s: Segment{11, 13},
-			tt: Segments{
-				Segment{120, 140},
-				Segment{20, 25},
+			weight: func(Segment) float64 { return 0.5 },
+			want: []WeightedRun{
+				{Segment{0, 5}, 0.5},
+				{Segment{5, 10}, 1.0},
+				{Segment{10, 15}, 0.5},
+				{Segment{20, 30}, 0.5},
 			},
-			want: true,
 		},
 		{
-			s:    Segment{11, 13},
-			tt:   Segments{},
-			want: true,
+			description: "adjacent pieces with equal weights are merged",
+			input: Segments{
+				Segment{0, 10},
+				Segment{10, 20},
+				Segment{15, 20},
+			},
+			weight: func(s Segment) float64 { return float64(s.Delta()) },
+			want: []WeightedRun{
+				{Segment{0, 15}, 10},
+				{Segment{15, 20}, 15},
+			},
 		},
 		{
-			s: Segment{1, 1},
-			tt: Segments{
-				Segment{0, 2},
-			},
-			want: false,
+			description: "empty input",
+			input:       nil,
+			weight:      func(Segment) float64 { return 1 },
+			want:        nil,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := test.s.IsIntersectionEmpty(test.tt); got != test.want {
-			t.Errorf("%s.IsIntersectionEmpty(%s) = %t, should be %t", test.s, test.tt, got, test.want)
+		if got := WeightedCoverage(test.input, test.weight); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: WeightedCoverage(%s) = %v, want %v", test.description, test.input, got, test.want)
 		}
 	}
 }
 
-func TestIsSubSegment(t *testing.T) {
+func TestFlattenWeighted(t *testing.T) {
 	testCases := []struct {
-		s, t Segment
-		want bool
+		description string
+		input       []WeightedSegment
+		want        []WeightedSegment
 	}{
 		{
-			s:    Segment{2, 30},
-			t:    Segment{20, 40},
-			want: false,
+			description: "overlapping weights are summed",
+			input: []WeightedSegment{
+				{Segment{5, 15}, 1},
+				{Segment{0, 10}, 2},
+			},
+			want: []WeightedSegment{
+				{Segment{0, 5}, 2},
+				{Segment{5, 10}, 3},
+				{Segment{10, 15}, 1},
+			},
 		},
 		{
-			s:    Segment{4, 6},
-			t:    Segment{3, 10},
-			want: true,
+			description: "touching segments with equal weights are merged, gaps are skipped",
+			input: []WeightedSegment{
+				{Segment{0, 2}, 4},
+				{Segment{2, 3}, 4},
+				{Segment{5, 6}, 4},
+				{Segment{7, 7}, 9},
+			},
+			want: []WeightedSegment{
+				{Segment{0, 3}, 4},
+				{Segment{5, 6}, 4},
+			},
 		},
 		{
-			s:    Segment{-10, 60},
-			t:    Segment{3, 10},
-			want: false,
+			description: "weights cancelling out still cover their piece",
+			input: []WeightedSegment{
+				{Segment{0, 4}, 3},
+				{Segment{2, 4}, -3},
+			},
+			want: []WeightedSegment{
+				{Segment{0, 2}, 3},
+				{Segment{2, 4}, 0},
+			},
+		},
+		{
+			description: "empty input",
+			input:       nil,
+			want:        nil,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := test.s.IsSubSegment(test.t); got != test.want {
-			t.Errorf("%s.IsSubSegment(%s) is %t, expected is %t", test.s, test.t, got, test.want)
+		if got := Flatten(test.input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Flatten(%v) = %v, want %v", test.description, test.input, got, test.want)
 		}
 	}
 }
 
-func TestIsPointInSegment(t *testing.T) {
+func TestIsPointInSegmentHalfOpen(t *testing.T) {
+	s := Segment{0, 5}
 	testCases := []struct {
-		s    Segment
 		p    int64
 		want bool
 	}{
-		{
-			s:    Segment{2, 30},
-			p:    11,
-			want: true},
-		{
-			s:    Segment{-10, 30},
-			p:    41,
-			want: false},
+		{p: -1, want: false},
+		{p: 0, want: true},
+		{p: 4, want: true},
+		{p: 5, want: false},
 	}
 
 	for _, test := range testCases {
-		if got := IsPointInSegment(test.p, test.s); got != test.want {
-			t.Errorf("IsPointInSegment(%d, %s) is %t, expected is %t", test.p, test.s, got, test.want)
+		if got := IsPointInSegmentHalfOpen(test.p, s); got != test.want {
+			t.Errorf("IsPointInSegmentHalfOpen(%d, %s) = %t, want %t", test.p, s, got, test.want)
 		}
 	}
+	if IsPointInSegmentHalfOpen(3, Segment{3, 3}) {
+		t.Errorf("IsPointInSegmentHalfOpen(3, %s) = true, want false", Segment{3, 3})
+	}
 }
 
-func TestIsPointInSegments(t *testing.T) {
+func TestRemoveOverlapsHalfOpen(t *testing.T) {
 	testCases := []struct {
-		ss   Segments
-		p    int64
-		want bool
+		input Segments
+		want  Segments
 	}{
 		{
-			ss: Segments{
-				Segment{2, 3},
-				Segment{1, 2},
-				Segment{4, 15}},
-			p:    11,
-			want: true},
+			input: Segments{
+				Segment{5, 10},
+				Segment{0, 5},
+			},
+			want: Segments{
+				Segment{0, 5},
+				Segment{5, 10},
+			},
+		},
 		{
-			ss: Segments{
-				Segment{2, 3},
-				Segment{1, 2},
-				Segment{4, 5}},
-			p:    41,
-			want: false,
+			input: Segments{
+				Segment{0, 6},
+				Segment{5, 10},
+				Segment{8, 9},
+			},
+			want: Segments{
+				Segment{0, 10},
+			},
+		},
+		{
+			input: Segments{
+				Segment{3, 3},
+				Segment{4, 2},
+				Segment{int64(math.MinInt64), 1},
+			},
+			want: Segments{
+				Segment{int64(math.MinInt64), 1},
+			},
+		},
+		{
+			input: nil,
+			want:  nil,
 		},
 	}
 
 	for _, test := range testCases {
-		if got := IsPointInSegments(test.p, test.ss); got != test.want {
-			t.Errorf("IsPointInSegments(%s, %d) = %t, want %t", test.ss, test.p, got, test.want)
+		if got := RemoveOverlapsHalfOpen(test.input); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("RemoveOverlapsHalfOpen(%s) is %s, should be %s", test.input, got, test.want)
 		}
 	}
 }